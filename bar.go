@@ -7,8 +7,10 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/injoyai/bar/internal/terminal"
 	"github.com/injoyai/bar/internal/util"
 	"github.com/injoyai/base/safe"
 	"github.com/injoyai/conv"
@@ -92,6 +94,14 @@ func WithSuffix(suffix string) Option {
 	}
 }
 
+// WithWidth 设置行宽,0表示自动获取终端宽度(默认),小于0表示不限制
+// 超出行宽时会依次省略靠后的格式,仍然超出则截断
+func WithWidth(width int) Option {
+	return func(b *Bar) {
+		b.SetWidth(width)
+	}
+}
+
 // WithWriter 设置writer
 func WithWriter(writer io.Writer) Option {
 	return func(b *Bar) {
@@ -108,14 +118,15 @@ func WithFinal(f Option) Option {
 func WithFinalLn() Option {
 	return func(b *Bar) {
 		b.OnFinal(func(b *Bar) {
-			if b.writer != nil {
+			//纯文本模式每次输出都带换行
+			if b.writer != nil && b.IsTTY() {
 				b.writer.Write([]byte("\n"))
 			}
 		})
 	}
 }
 
-// WithAutoFlush 设置后自动刷新
+// WithAutoFlush 设置后自动刷新,受最大刷新频率限制,未刷新的内容由渲染循环补上
 func WithAutoFlush() Option {
 	return func(b *Bar) {
 		b.OnSet(func(b *Bar) {
@@ -124,21 +135,19 @@ func WithAutoFlush() Option {
 	}
 }
 
-// WithIntervalFlush 设置定时刷新
+// WithIntervalFlush 设置定时刷新,内容没有变化也会刷新(例如时间相关的格式)
 func WithIntervalFlush(interval time.Duration) Option {
 	return func(b *Bar) {
-		go func() {
-			t := time.NewTicker(interval)
-			defer t.Stop()
-			for {
-				select {
-				case <-b.Done():
-					return
-				case <-t.C:
-					b.Flush()
-				}
-			}
-		}()
+		b.interval = interval
+		b.run()
+	}
+}
+
+// WithRefreshRate 设置最大刷新频率(每秒的帧数),默认 DefaultRefreshRate ,小于等于0表示不限制
+// 超过频率的 Flush 只标记需要刷新,由渲染循环按频率刷新,完成时总会刷新最后一帧
+func WithRefreshRate(fps int) Option {
+	return func(b *Bar) {
+		b.SetRefreshRate(fps)
 	}
 }
 
@@ -151,17 +160,36 @@ func WithFlush() Option {
 
 func New(op ...Option) *Bar {
 	b := &Bar{
-		current:     0,
-		total:       0,
-		formatSplit: "  ",
-		writer:      os.Stdout,
-		startTime:   time.Now(),
-		Closer:      safe.NewCloser(),
+		formatSplit:   "  ",
+		writer:        os.Stdout,
+		plainStep:     DefaultPlainStep,
+		plainInterval: DefaultPlainInterval,
+		refresh:       time.Second / DefaultRefreshRate,
+		clock:         SystemClock,
+		startTime:     SystemClock.Now(),
+		Closer:        safe.NewCloser(),
 	}
 	b.SetCloseFunc(func(err error) error {
+		//直接关闭时,完成或不确定模式记为成功,否则记为中止,需要在最后一帧之前设置,显示结束的状态
+		state, stateErr := StateAborted, ErrAborted
+		if b.complete() || b.Indeterminate() {
+			state, stateErr = StateSucceeded, nil
+		}
+		if b.setState(state, stateErr) {
+			b.dirty.Store(true)
+		}
+		//刷新还未刷新的最后一帧
+		b.mu.Lock()
+		if !b.finished && b.dirty.Load() {
+			b.draw(true)
+		}
+		b.finished = true
+		b.mu.Unlock()
+		b.saveCheckpoint(true)
 		if b.onFinal != nil {
 			b.onFinal(b)
 		}
+		b.doDone()
 		return nil
 	})
 	WithFinalLn()(b)
@@ -173,34 +201,72 @@ func New(op ...Option) *Bar {
 }
 
 type Bar struct {
-	current     int64          //当前数量
-	total       int64          //总数量
-	prefix      string         //前缀
-	suffix      string         //后缀
-	format      Format         //格式化
-	formatSplit string         //分隔符
-	writer      io.Writer      //输出
-	onChange    []func(b *Bar) //设置事件
-	onFinal     func(b *Bar)   //完成事件
-
-	startTime time.Time  //开始时间
-	last      int64      //最后一次增加的值
-	lastTime  time.Time  //最后一次时间
-	mu        sync.Mutex //并发锁
+	current     atomic.Int64                   //当前数量,超过总数量的部分在读取时截断
+	total       atomic.Int64                   //总数量
+	prefix      string                         //前缀
+	suffix      string                         //后缀
+	format      Format                         //格式化
+	formatSplit string                         //分隔符
+	writer      io.Writer                      //输出
+	onChange    atomic.Pointer[[]func(b *Bar)] //设置事件,写时复制
+	onFinal     func(b *Bar)                   //完成事件
+	onDone      []func(b *Bar)                 //内部的结束事件,在完成事件之后执行,不会被 OnFinal 替换
+	doneMu      sync.Mutex                     //结束事件锁
+	done        bool                           //结束事件是否已执行
+	width       int                            //行宽,0表示自动获取终端宽度,小于0表示不限制
+
+	flex    int  //弹性格式的可用宽度,仅在渲染时有效
+	flexing bool //弹性格式在测量时标记自己
+
+	ownAffix bool //格式自己输出前缀和后缀(模板中使用了 .Prefix .Suffix),渲染时标记
+
+	mode          int           //输出模式,自动/终端/纯文本
+	plainStep     float64       //纯文本模式,每前进多少输出一行
+	plainInterval time.Duration //纯文本模式,每隔多久输出一行
+	plainRate     float64       //纯文本模式,最后一次输出的进度
+	plainTime     time.Time     //纯文本模式,最后一次输出的时间
+
+	refresh   time.Duration //两次刷新的最小间隔,0表示不限制
+	interval  time.Duration //定时刷新的间隔,0表示不定时刷新
+	lastFlush atomic.Int64  //最后一次刷新的时间(纳秒)
+	dirty     atomic.Bool   //是否有未刷新的变化
+	finished  bool          //最后一帧是否已刷新
+	looping   atomic.Bool   //渲染循环是否在运行,同一时间只有一个
+
+	hookMu      sync.Mutex  //事件执行锁,同一时间只有一个协程执行事件
+	hookSetMu   sync.Mutex  //事件设置锁
+	hookPending atomic.Bool //是否有未执行的事件,多次设置合并成一次执行
+
+	clock     Clock         //时钟
+	startTime time.Time     //开始时间
+	paused    time.Duration //累计暂停的时间
+	pauseTime time.Time     //本次暂停的开始时间,零值表示未暂停
+	pauseMu   sync.Mutex    //暂停锁
+	state     State         //结束的状态
+	stateErr  error         //失败的错误
+	stateMu   sync.Mutex    //状态锁
+
+	checkpoint string            //检查点文件
+	lastSave   atomic.Int64      //最后一次保存检查点的时间(纳秒)
+	origin     time.Time         //最初的开始时间,恢复检查点时设置
+	offset     time.Duration     //恢复的累计耗时,之后设置时钟时保留
+	fields     map[string]*int64 //绑定的自定义字段
+	restored   map[string]int64  //恢复的自定义字段,用于之后绑定的字段
+	fieldMu    sync.Mutex        //字段锁
+	last       atomic.Int64      //最后一次增加的值
+	lastTime   atomic.Int64      //最后一次变化被刷新的时间(纳秒)
+	mu         sync.Mutex        //刷新锁
 
 	*safe.Closer //closer
 }
 
+// Add 增加数量,无锁,不分配内存,不读取时钟,只标记需要刷新
 func (this *Bar) Add(n int64) *Bar {
-	this.mu.Lock()
-	this.current = this.current + n
-	if this.current > this.total {
-		this.current = this.total
+	this.current.Add(n)
+	this.last.Store(n)
+	if !this.dirty.Load() {
+		this.dirty.Store(true)
 	}
-	this.last = n
-	this.lastTime = time.Now()
-	this.mu.Unlock()
-
 	return this.doOnchange()
 }
 
@@ -209,21 +275,19 @@ func (this *Bar) Set(current int64) *Bar {
 }
 
 func (this *Bar) SetCurrent(current int64) *Bar {
-	this.mu.Lock()
-	defer this.mu.Unlock()
-
-	if current > this.total {
-		current = this.total
+	if total := this.total.Load(); total > 0 && current > total {
+		current = total
 	}
-	this.last = current - this.current
-	this.lastTime = time.Now()
-	this.current = current
-
+	old := this.current.Swap(current)
+	this.last.Store(current - old)
+	this.dirty.Store(true)
 	return this.doOnchange()
 }
 
+// SetTotal 设置总数量,小于等于0表示总数量未知(不确定模式),
+// 此时数量会一直增长,需要主动调用Close结束,后续设置了总数量则切换成正常模式
 func (this *Bar) SetTotal(total int64) {
-	this.total = total
+	this.total.Store(total)
 }
 
 func (this *Bar) SetFormat(fs ...Format) {
@@ -231,16 +295,10 @@ func (this *Bar) SetFormat(fs ...Format) {
 	case 0:
 		this.format = func(b *Bar) string { return "" }
 
-	case 1:
-		this.format = fs[0]
-
 	default:
 		ls := make([]string, len(fs))
 		this.format = func(b *Bar) string {
-			for i, v := range fs {
-				ls[i] = v(b)
-			}
-			return strings.Join(ls, this.formatSplit)
+			return b.layout(fs, ls)
 		}
 
 	}
@@ -258,27 +316,74 @@ func (this *Bar) SetSuffix(suffix string) {
 	this.suffix = suffix
 }
 
+// SetWidth 设置行宽,0表示自动获取终端宽度,小于0表示不限制
+func (this *Bar) SetWidth(width int) {
+	this.width = width
+}
+
 func (this *Bar) SetWriter(w io.Writer) {
 	this.writer = w
 }
 
 func (this *Bar) OnSet(f func(b *Bar)) {
-	this.onChange = append(this.onChange, f)
+	this.OnChange(f)
 }
 
+// OnChange 设置事件,数量变化时执行,并发设置时会合并执行,同一时间只有一个协程在执行
 func (this *Bar) OnChange(f func(b *Bar)) {
-	this.onChange = append(this.onChange, f)
+	this.hookSetMu.Lock()
+	defer this.hookSetMu.Unlock()
+	var ls []func(b *Bar)
+	if old := this.onChange.Load(); old != nil {
+		ls = append(ls, *old...)
+	}
+	ls = append(ls, f)
+	this.onChange.Store(&ls)
 }
 
 func (this *Bar) OnFinal(f Option) {
 	this.onFinal = f
 }
 
+// whenDone 增加内部的结束事件,在完成事件之后执行,已经结束时立即执行
+func (this *Bar) whenDone(f func(b *Bar)) {
+	this.doneMu.Lock()
+	if !this.done {
+		this.onDone = append(this.onDone, f)
+		this.doneMu.Unlock()
+		return
+	}
+	this.doneMu.Unlock()
+	f(this)
+}
+
+// doDone 执行内部的结束事件
+func (this *Bar) doDone() {
+	this.doneMu.Lock()
+	this.done = true
+	ls := this.onDone
+	this.onDone = nil
+	this.doneMu.Unlock()
+	for _, f := range ls {
+		f(this)
+	}
+}
+
+// doOnchange 执行设置事件,已经有协程在执行时,只标记待执行,由该协程再执行一次
 func (this *Bar) doOnchange() *Bar {
-	for _, f := range this.onChange {
-		if f != nil {
-			f(this)
+	if this.onChange.Load() == nil {
+		return this
+	}
+	this.hookPending.Store(true)
+	for this.hookPending.Load() && this.hookMu.TryLock() {
+		for this.hookPending.Swap(false) {
+			for _, f := range *this.onChange.Load() {
+				if f != nil {
+					f(this)
+				}
+			}
 		}
+		this.hookMu.Unlock()
 	}
 	return this
 }
@@ -289,69 +394,97 @@ func (this *Bar) doOnchange() *Bar {
 
  */
 
+func (this *Bar) Prefix() string {
+	return this.prefix
+}
+
+func (this *Bar) Suffix() string {
+	return this.suffix
+}
+
 func (this *Bar) Last() int64 {
-	return this.last
+	return this.last.Load()
 }
 
 func (this *Bar) Current() int64 {
-	return this.current
+	current := this.current.Load()
+	if total := this.total.Load(); total > 0 && current > total {
+		return total
+	}
+	return current
 }
 
 func (this *Bar) Total() int64 {
-	return this.total
+	return this.total.Load()
+}
+
+// Indeterminate 是否是不确定模式,即总数量未知
+func (this *Bar) Indeterminate() bool {
+	return this.Total() <= 0
 }
 
 func (this *Bar) Rate() float64 {
-	total := this.total
-	if total == 0 {
+	total := this.Total()
+	if total <= 0 {
 		return 0
 	}
-	return float64(this.current) / float64(total)
+	return float64(this.Current()) / float64(total)
 }
 
 func (this *Bar) StartTime() time.Time {
 	return this.startTime
 }
 
+// LastTime 最后一次变化的时间,在刷新时记录,精度为刷新的间隔
 func (this *Bar) LastTime() time.Time {
-	return this.lastTime
+	if n := this.lastTime.Load(); n != 0 {
+		return time.Unix(0, n)
+	}
+	return time.Time{}
 }
 
 func (this *Bar) Logf(format string, a ...any) *Bar {
-	s := "\r\033[K" + fmt.Sprintf(format, a...)
+	s := fmt.Sprintf(format, a...)
 	if len(s) == 0 || s[len(s)-1] != '\n' {
 		s += "\n"
 	}
+	if this.IsTTY() {
+		s = "\r\033[K" + s
+	}
 	this.writer.Write([]byte(s))
 	return this.doOnchange()
 }
 
 func (this *Bar) Log(a ...any) *Bar {
-	s := "\r\033[K" + fmt.Sprintln(a...)
+	s := fmt.Sprintln(a...)
+	if this.IsTTY() {
+		s = "\r\033[K" + s
+	}
 	this.writer.Write([]byte(s))
 	return this.doOnchange()
 }
 
-func (this *Bar) Flush() *Bar {
-	this.mu.Lock()
-	defer this.mu.Unlock()
-
-	if this.Closed() || this.writer == nil {
-		return this
+// String 渲染当前的进度,终端模式下以\r\033[K开头,纯文本模式不带控制符
+func (this *Bar) String() string {
+	this.ownAffix = false
+	s := this.format(this)
+	if !this.ownAffix {
+		s = this.prefix + s + this.suffix
 	}
-
-	s := this.String()
-	this.writer.Write([]byte(s))
-
-	if this.current >= this.total {
-		this.Close()
+	state := this.stateText()
+	if state != "" {
+		state = this.formatSplit + state
+	}
+	if !this.IsTTY() {
+		return terminal.StripANSI(strings.TrimLeft(s+state, "\r"))
+	}
+	if w := this.Width(); w > 0 {
+		//结束的状态优先显示,宽度不足时截断前面的内容
+		s = terminal.Truncate(s, max(w-terminal.Width(state), 0))
+		s = terminal.Truncate(s+state, w)
+	} else {
+		s += state
 	}
-
-	return this
-}
-
-func (this *Bar) String() string {
-	s := this.prefix + this.format(this) + this.suffix
 	if s == "" || s[0] != '\r' {
 		s = "\r\033[K" + s
 	}
@@ -370,8 +503,22 @@ func (this *Bar) String() string {
 
  */
 
+// Download 下载文件,根据响应的Content-Length设置总数量,按写入的字节显示进度
+// 存在未完成的下载时(filename.downloading)会断点续传,进度从已下载的位置开始
 func (this *Bar) Download(source, filename string, proxy ...string) (int64, error) {
-	defer this.Close()
+	return this.DownloadParallel(source, filename, 1, proxy...)
+}
+
+// DownloadParallel 多连接分段下载文件,n为分段(连接)数量,所有分段的进度汇总到当前进度条
+// 服务端不支持Range时退化成单连接下载,n<=1时同 Download
+func (this *Bar) DownloadParallel(source, filename string, n int, proxy ...string) (size int64, err error) {
+	defer func() {
+		if err != nil {
+			this.Fail(err)
+			return
+		}
+		this.Close()
+	}()
 
 	//下载大文件的时候需要设置长的超时时间
 	h := util.NewClient().SetTimeout(0)
@@ -383,26 +530,24 @@ func (this *Bar) Download(source, filename string, proxy ...string) (int64, erro
 		return 0, err
 	}
 
-	return h.GetToFile(source, filename)
-}
-
-func (this *Bar) Copy(w io.Writer, r io.Reader) (int64, error) {
-	return this.CopyN(w, r, 4<<10)
-}
-
-func (this *Bar) CopyN(w io.Writer, r io.Reader, bufSize int64) (int64, error) {
-	reader := &Reader{Reader: r, Bar: this}
-	return io.CopyN(w, reader, bufSize)
-}
-
-type Reader struct {
-	io.Reader
-	*Bar
-}
-
-func (this *Reader) Read(p []byte) (n int, err error) {
-	n, err = this.Reader.Read(p)
-	this.Bar.Add(int64(n))
-	this.Bar.Flush()
-	return
+	size, err = h.GetToFileParallel(source, filename, n, &util.Progress{
+		SetTotal: func(total int64) {
+			if total > 0 {
+				this.SetTotal(total)
+			}
+		},
+		SetCurrent: func(current int64) {
+			this.SetCurrent(current)
+		},
+		Add: func(n int64) {
+			this.Add(n)
+			this.Flush()
+		},
+	})
+	if err == nil && this.Indeterminate() {
+		//未知大小的下载完成后,以实际大小作为总数量,刷新最后的进度
+		this.SetTotal(size)
+		this.Flush()
+	}
+	return size, err
 }
@@ -0,0 +1,63 @@
+package bar
+
+import (
+	"io"
+	"testing"
+	"time"
+)
+
+func newBenchBar(op ...Option) *Bar {
+	return New(
+		WithTotal(1<<62),
+		WithWriter(io.Discard),
+		WithTTY(),
+		WithOption(op...),
+	)
+}
+
+func BenchmarkAdd(b *testing.B) {
+	x := newBenchBar()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		x.Add(1)
+	}
+}
+
+func BenchmarkAddParallel(b *testing.B) {
+	x := newBenchBar()
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			x.Add(1)
+		}
+	})
+}
+
+func BenchmarkAddParallelHook(b *testing.B) {
+	x := newBenchBar()
+	x.OnChange(func(b *Bar) { time.Sleep(0) })
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			x.Add(1)
+		}
+	})
+}
+
+func BenchmarkAddFlushParallel(b *testing.B) {
+	x := newBenchBar()
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			x.Add(1).Flush()
+		}
+	})
+}
+
+func BenchmarkSetCurrent(b *testing.B) {
+	x := newBenchBar()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		x.SetCurrent(int64(i))
+	}
+}
@@ -0,0 +1,120 @@
+package bartest
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/injoyai/bar"
+)
+
+func TestClockTicker(t *testing.T) {
+	c := NewClock()
+	tk := c.NewTicker(time.Second)
+	defer tk.Stop()
+
+	c.Add(500 * time.Millisecond)
+	select {
+	case <-tk.C():
+		t.Fatal("ticker fired early")
+	default:
+	}
+
+	c.Add(500 * time.Millisecond)
+	select {
+	case v := <-tk.C():
+		if want := Start.Add(time.Second); !v.Equal(want) {
+			t.Fatalf("tick time: got %v, want %v", v, want)
+		}
+	default:
+		t.Fatal("ticker did not fire")
+	}
+}
+
+func TestRecorderFrames(t *testing.T) {
+	r := NewRecorder()
+	r.Write([]byte("\r\033[Ka"))
+	r.Write([]byte("\r\033[Kb\n"))
+	r.Write([]byte("c\n"))
+	got := r.Frames()
+	want := []string{"a", "b", "c"}
+	if len(got) != len(want) {
+		t.Fatalf("frames: got %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("frames: got %q, want %q", got, want)
+		}
+	}
+	if r.Last() != "c" {
+		t.Fatalf("last: got %q", r.Last())
+	}
+}
+
+// TestGolden 默认格式在时钟开始时刻和之后的渲染结果
+func TestGolden(t *testing.T) {
+	b, c, r := New(bar.WithTotal(100), bar.WithFormat(
+		bar.WithRateSize(),
+		bar.WithSpeed(),
+		bar.WithSpeedAvg(),
+		bar.WithSpeedUnitAvg(),
+		bar.WithUsed(),
+		bar.WithRemain(),
+	))
+	b.Flush()
+	c.Add(2 * time.Second)
+	b.Add(50).Flush()
+	c.Add(2 * time.Second)
+	b.Add(50).Flush()
+
+	want := []string{
+		"0/100  0.0/s  0.0/s  0.0B/s  0s  -",
+		"50/100  25.0/s  25.0/s  25.0B/s  2s  2s",
+		"100/100  25.0/s  25.0/s  25.0B/s  4s  0s  ✓",
+	}
+	got := r.Frames()
+	if len(got) != len(want) {
+		t.Fatalf("frames: got %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("frame %d: got %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestIntervalFlush(t *testing.T) {
+	b, c, r := New(bar.WithFormat(bar.WithUsed()), bar.WithIntervalFlush(time.Second))
+	defer b.Close()
+	c.Add(time.Second)
+	waitFrames(t, r, 1)
+	c.Add(time.Second)
+	waitFrames(t, r, 2)
+	if got := r.Frames(); got[0] != "1s" || got[1] != "2s" {
+		t.Fatalf("frames: got %q", got)
+	}
+}
+
+// waitFrames 等待渲染循环输出n帧
+func waitFrames(t *testing.T, r *Recorder, n int) {
+	deadline := time.Now().Add(time.Second)
+	for len(r.Frames()) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for %d frames, got %q", n, r.Frames())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestDefaultFormatAtStart(t *testing.T) {
+	for _, op := range []bar.Option{bar.WithFormatDefault(), bar.WithFormatDefaultUnit()} {
+		b, _, r := New(bar.WithTotal(100), op)
+		b.Flush()
+		b.Add(0).Flush()
+		for _, v := range r.Frames() {
+			if strings.Contains(v, "Inf") || strings.Contains(v, "NaN") {
+				t.Fatalf("frame: %q", v)
+			}
+		}
+	}
+}
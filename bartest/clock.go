@@ -0,0 +1,107 @@
+// Package bartest 进度条的测试工具,可控的时钟和记录渲染帧的writer,用于编写确定的渲染结果测试
+//
+//	b, c, r := bartest.New(bar.WithTotal(100), bar.WithFormat(bar.WithPlan(), bar.WithUsed()))
+//	c.Add(time.Second)
+//	b.Add(10).Flush()
+//	r.Last() // [#####     ]  1s
+package bartest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/injoyai/bar"
+)
+
+// Start 时钟默认的开始时间
+var Start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// NewClock 新建可控的时钟,不传时间则从 Start 开始,时间只会通过 Add 和 Set 改变
+func NewClock(start ...time.Time) *Clock {
+	c := &Clock{now: Start}
+	if len(start) > 0 {
+		c.now = start[0]
+	}
+	return c
+}
+
+// Clock 可控的时钟,实现了 bar.Clock
+type Clock struct {
+	now     time.Time
+	tickers []*ticker
+	mu      sync.Mutex
+}
+
+func (this *Clock) Now() time.Time {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	return this.now
+}
+
+// NewTicker 新建定时器,时钟前进时触发到期的定时器,和 time.Ticker 一样,来不及接收的时间会丢弃
+func (this *Clock) NewTicker(d time.Duration) bar.Ticker {
+	if d <= 0 {
+		panic("bartest: non-positive interval for NewTicker")
+	}
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	t := &ticker{
+		clock:  this,
+		c:      make(chan time.Time, 1),
+		period: d,
+		next:   this.now.Add(d),
+	}
+	this.tickers = append(this.tickers, t)
+	return t
+}
+
+// Add 时钟前进d,并触发到期的定时器
+func (this *Clock) Add(d time.Duration) {
+	this.Set(this.Now().Add(d))
+}
+
+// Set 设置时钟的时间,时间晚于当前时间时触发到期的定时器
+func (this *Clock) Set(t time.Time) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	this.now = t
+	for _, v := range this.tickers {
+		if v.next.After(t) {
+			continue
+		}
+		select {
+		case v.c <- t:
+		default:
+		}
+		for !v.next.After(t) {
+			v.next = v.next.Add(v.period)
+		}
+	}
+}
+
+// remove 移除停止的定时器
+func (this *Clock) remove(t *ticker) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	for i, v := range this.tickers {
+		if v == t {
+			this.tickers = append(this.tickers[:i], this.tickers[i+1:]...)
+			return
+		}
+	}
+}
+
+type ticker struct {
+	clock  *Clock
+	c      chan time.Time
+	period time.Duration
+	next   time.Time
+}
+
+func (this *ticker) C() <-chan time.Time {
+	return this.c
+}
+
+func (this *ticker) Stop() {
+	this.clock.remove(this)
+}
@@ -0,0 +1,87 @@
+package bartest
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/injoyai/bar"
+)
+
+// New 新建用于测试的进度条,使用可控的时钟和记录渲染帧的writer,
+// 终端模式,不限制行宽,不限制刷新频率(每次 Flush 都会渲染一帧),op 在这些设置之后执行
+func New(op ...bar.Option) (*bar.Bar, *Clock, *Recorder) {
+	c := NewClock()
+	r := NewRecorder()
+	b := bar.New(
+		bar.WithClock(c),
+		bar.WithWriter(r),
+		bar.WithTTY(),
+		bar.WithWidth(-1),
+		bar.WithRefreshRate(0),
+		bar.WithOption(op...),
+	)
+	return b, c, r
+}
+
+// NewRecorder 新建记录渲染帧的writer
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Recorder 记录渲染帧的writer,终端模式以\r\033[K分帧,纯文本模式以换行分帧,帧内容不包括控制符
+type Recorder struct {
+	buf    strings.Builder
+	frames []string
+	mu     sync.Mutex
+}
+
+func (this *Recorder) Write(p []byte) (int, error) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	this.buf.Write(p)
+	this.frames = append(this.frames, splitFrames(string(p))...)
+	return len(p), nil
+}
+
+// Frames 所有渲染的帧
+func (this *Recorder) Frames() []string {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	return append([]string(nil), this.frames...)
+}
+
+// Last 最后一帧,没有则返回空
+func (this *Recorder) Last() string {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	if len(this.frames) == 0 {
+		return ""
+	}
+	return this.frames[len(this.frames)-1]
+}
+
+// String 写入的原始内容,包括控制符
+func (this *Recorder) String() string {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	return this.buf.String()
+}
+
+// Reset 清空记录的内容
+func (this *Recorder) Reset() {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	this.buf.Reset()
+	this.frames = nil
+}
+
+// splitFrames 按\r\033[K和换行分帧,去掉空帧
+func splitFrames(s string) []string {
+	var ls []string
+	for _, v := range strings.Split(strings.ReplaceAll(s, "\r\033[K", "\n"), "\n") {
+		if v = strings.TrimPrefix(v, "\r"); v != "" {
+			ls = append(ls, v)
+		}
+	}
+	return ls
+}
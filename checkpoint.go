@@ -0,0 +1,171 @@
+package bar
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultCheckpointInterval 默认保存检查点的最小间隔
+const DefaultCheckpointInterval = time.Second
+
+// Checkpoint 进度条的检查点,用于进程重启后恢复进度,耗时,速度和剩余时间
+type Checkpoint struct {
+	Current   int64            `json:"current"`          //当前数量
+	Total     int64            `json:"total"`            //总数量
+	StartTime time.Time        `json:"startTime"`        //最初的开始时间
+	Elapsed   time.Duration    `json:"elapsed"`          //累计耗时,不包括暂停和进程停止的时间
+	Fields    map[string]int64 `json:"fields,omitempty"` //自定义字段,见 BindField
+}
+
+// LoadCheckpoint 读取检查点文件
+func LoadCheckpoint(filename string) (*Checkpoint, error) {
+	bs, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	c := new(Checkpoint)
+	err = json.Unmarshal(bs, c)
+	return c, err
+}
+
+// Save 保存检查点到文件,先写临时文件再重命名,避免写一半时进程退出导致文件损坏
+func (this *Checkpoint) Save(filename string) error {
+	return saveJSON(filename, this)
+}
+
+// saveJSON 保存json文件,先写临时文件再重命名
+func saveJSON(filename string, v any) error {
+	bs, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(filename), os.ModePerm); err != nil {
+		return err
+	}
+	tmp := filename + ".tmp"
+	if err = os.WriteFile(tmp, bs, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
+
+// WithCheckpoint 使用检查点文件,文件存在时恢复进度,运行中定时保存,
+// 成功完成时删除文件,失败或中止时保留,下次启动继续,绑定的自定义字段(BindField)会一起恢复,和设置的先后顺序无关
+func WithCheckpoint(filename string) Option {
+	return func(b *Bar) {
+		b.checkpoint = filename
+		if c, err := LoadCheckpoint(filename); err == nil {
+			b.Restore(c)
+		}
+	}
+}
+
+// WithBindField 绑定自定义字段,见 BindField
+func WithBindField(name string, p *int64) Option {
+	return func(b *Bar) {
+		b.BindField(name, p)
+	}
+}
+
+// BindField 绑定自定义字段,保存检查点时保存字段的值,恢复时写回,需传指针,例如 WithCustomSize 的大小
+func (this *Bar) BindField(name string, p *int64) {
+	this.fieldMu.Lock()
+	defer this.fieldMu.Unlock()
+	if this.fields == nil {
+		this.fields = map[string]*int64{}
+	}
+	this.fields[name] = p
+	//先恢复了检查点,再绑定的字段
+	if v, ok := this.restored[name]; ok {
+		atomic.StoreInt64(p, v)
+	}
+}
+
+// Checkpoint 当前进度的检查点
+func (this *Bar) Checkpoint() *Checkpoint {
+	c := &Checkpoint{
+		Current:   this.Current(),
+		Total:     this.Total(),
+		StartTime: this.originTime(),
+		Elapsed:   this.Elapsed(),
+	}
+	this.fieldMu.Lock()
+	defer this.fieldMu.Unlock()
+	if len(this.fields) > 0 {
+		c.Fields = make(map[string]int64, len(this.fields))
+		for k, p := range this.fields {
+			c.Fields[k] = atomic.LoadInt64(p)
+		}
+	}
+	return c
+}
+
+// Restore 从检查点恢复进度,耗时从检查点的累计耗时继续,不触发设置事件,速度不会因为恢复的数量突增
+func (this *Bar) Restore(c *Checkpoint) {
+	if c == nil {
+		return
+	}
+	this.total.Store(c.Total)
+	this.current.Store(c.Current)
+	this.last.Store(0)
+
+	this.pauseMu.Lock()
+	this.offset = c.Elapsed
+	this.startTime = this.Now().Add(-c.Elapsed)
+	this.paused = 0
+	if !this.pauseTime.IsZero() {
+		this.pauseTime = this.Now()
+	}
+	this.origin = c.StartTime
+	this.pauseMu.Unlock()
+
+	this.fieldMu.Lock()
+	this.restored = c.Fields
+	for k, p := range this.fields {
+		if v, ok := c.Fields[k]; ok {
+			atomic.StoreInt64(p, v)
+		}
+	}
+	this.fieldMu.Unlock()
+
+	this.dirty.Store(true)
+}
+
+// SaveCheckpoint 保存当前进度到检查点文件
+func (this *Bar) SaveCheckpoint(filename string) error {
+	return this.Checkpoint().Save(filename)
+}
+
+// originTime 最初的开始时间,恢复过检查点时为检查点的开始时间
+func (this *Bar) originTime() time.Time {
+	this.pauseMu.Lock()
+	defer this.pauseMu.Unlock()
+	if !this.origin.IsZero() {
+		return this.origin
+	}
+	return this.startTime
+}
+
+// saveCheckpoint 定时保存检查点,final为结束时保存,成功结束时删除检查点文件
+func (this *Bar) saveCheckpoint(final bool) {
+	if this.checkpoint == "" {
+		return
+	}
+	if final {
+		if this.State() == StateSucceeded {
+			os.Remove(this.checkpoint)
+			return
+		}
+		this.SaveCheckpoint(this.checkpoint)
+		return
+	}
+	now := this.Now().UnixNano()
+	last := this.lastSave.Load()
+	if time.Duration(now-last) < DefaultCheckpointInterval || !this.lastSave.CompareAndSwap(last, now) {
+		return
+	}
+	this.SaveCheckpoint(this.checkpoint)
+}
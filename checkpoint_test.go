@@ -0,0 +1,93 @@
+package bar_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/injoyai/bar"
+	"github.com/injoyai/bar/bartest"
+)
+
+// TestCheckpoint 失败时保留检查点,下次恢复进度,耗时和绑定的字段,成功完成时删除检查点
+func TestCheckpoint(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "bar.json")
+
+	//第一次运行,失败时保留检查点
+	var size int64
+	b, c, _ := bartest.New(
+		bar.WithTotal(100),
+		bar.WithFormat(bar.WithRateSize()),
+		bar.WithCheckpoint(filename),
+		bar.WithBindField("size", &size),
+	)
+	c.Add(10 * time.Second)
+	atomic.StoreInt64(&size, 1024)
+	b.Add(30).Flush()
+	b.Fail(errors.New("断开"))
+	cp, err := bar.LoadCheckpoint(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cp.Current != 30 || cp.Total != 100 || cp.Elapsed != 10*time.Second || cp.Fields["size"] != 1024 {
+		t.Fatalf("checkpoint: %+v", cp)
+	}
+
+	//第二次运行,字段在恢复之前和之后绑定都能恢复
+	var before, after int64
+	b, c, r := bartest.New(
+		bar.WithFormat(bar.WithRateSize(), bar.WithUsed()),
+		bar.WithBindField("size", &before),
+		bar.WithCheckpoint(filename),
+		bar.WithBindField("size", &after),
+	)
+	if b.Current() != 30 || b.Total() != 100 || b.Elapsed() != 10*time.Second {
+		t.Fatalf("restore: current %d, total %d, elapsed %v", b.Current(), b.Total(), b.Elapsed())
+	}
+	if before != 1024 || after != 1024 {
+		t.Fatalf("fields: before %d, after %d", before, after)
+	}
+	if !b.StartTime().Equal(bartest.Start.Add(-10*time.Second)) || !b.Checkpoint().StartTime.Equal(cp.StartTime) {
+		t.Fatalf("start time: %v, origin %v", b.StartTime(), b.Checkpoint().StartTime)
+	}
+
+	//耗时从恢复的耗时继续
+	c.Add(5 * time.Second)
+	b.Add(20).Flush()
+	if got, want := r.Last(), "50/100  15s"; got != want {
+		t.Fatalf("frame: got %q, want %q", got, want)
+	}
+	if _, err := os.Stat(filename); err != nil {
+		t.Fatalf("checkpoint removed before finish: %v", err)
+	}
+	b.Finish()
+	if _, err := os.Stat(filename); !os.IsNotExist(err) {
+		t.Fatalf("checkpoint not removed after finish: %v", err)
+	}
+}
+
+// TestCheckpointClockOrder 在 WithCheckpoint 之后设置时钟,保留恢复的耗时
+func TestCheckpointClockOrder(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "bar.json")
+	cp := &bar.Checkpoint{Current: 30, Total: 100, StartTime: bartest.Start, Elapsed: 10 * time.Second}
+	if err := cp.Save(filename); err != nil {
+		t.Fatal(err)
+	}
+	c := bartest.NewClock()
+	b := bar.New(
+		bar.WithWriter(bartest.NewRecorder()),
+		bar.WithCheckpoint(filename),
+		bar.WithClock(c),
+	)
+	defer b.Close()
+	if b.Elapsed() != 10*time.Second {
+		t.Fatalf("elapsed: %v", b.Elapsed())
+	}
+	c.Add(time.Second)
+	if b.Elapsed() != 11*time.Second {
+		t.Fatalf("elapsed: %v", b.Elapsed())
+	}
+}
@@ -0,0 +1,61 @@
+package bar
+
+import (
+	"time"
+)
+
+// Clock 时钟,进度条的计时,时间相关的格式和定时刷新都通过时钟获取时间,
+// 测试时可以替换成可控的时钟,见 bartest.Clock
+type Clock interface {
+	// Now 当前时间
+	Now() time.Time
+	// NewTicker 新建定时器,同 time.NewTicker
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker 定时器,同 time.Ticker
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// SystemClock 系统时钟,默认的时钟
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) NewTicker(d time.Duration) Ticker { return systemTicker{time.NewTicker(d)} }
+
+type systemTicker struct{ *time.Ticker }
+
+func (this systemTicker) C() <-chan time.Time { return this.Ticker.C }
+
+// WithClock 设置时钟,开始时间重置为时钟的当前时间,需要在定时刷新等选项之前设置,
+// 在 WithCheckpoint 之后设置时,保留恢复的累计耗时
+func WithClock(c Clock) Option {
+	return func(b *Bar) {
+		b.SetClock(c)
+	}
+}
+
+// SetClock 设置时钟,nil表示系统时钟,开始时间重置为时钟的当前时间,恢复过检查点时保留恢复的累计耗时
+func (this *Bar) SetClock(c Clock) {
+	if c == nil {
+		c = SystemClock
+	}
+	this.pauseMu.Lock()
+	defer this.pauseMu.Unlock()
+	this.clock = c
+	this.startTime = c.Now().Add(-this.offset)
+	this.paused = 0
+	if !this.pauseTime.IsZero() {
+		this.pauseTime = c.Now()
+	}
+}
+
+// Now 时钟的当前时间
+func (this *Bar) Now() time.Time {
+	return this.clock.Now()
+}
@@ -1,26 +1,126 @@
 package bar
 
-import "github.com/injoyai/base/chans"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/injoyai/base/chans"
+)
+
+// ErrPolicy 任务出错时的处理策略
+type ErrPolicy int
+
+const (
+	ErrContinue ErrPolicy = iota //继续执行剩余的任务
+	ErrCancel                    //取消剩余的任务,类似errgroup
+)
 
 func NewCoroutine(total, limit int, op ...Option) *Coroutine {
-	b := New(
+	return NewCoroutineContext(context.Background(), total, limit, ErrContinue,
+		WithFormatDefault(),
+		WithOption(op...),
+	)
+}
+
+// NewCoroutineContext 基于context的协程模式,任务出错时根据策略决定是否取消剩余的任务,
+// context取消后,未开始的任务不再执行,Wait返回所有任务的错误,进度条默认显示成功和失败的数量,
+// limit为最大并发数量,<=0时不限制(按total),total也未知时为1
+func NewCoroutineContext(ctx context.Context, total, limit int, policy ErrPolicy, op ...Option) *Coroutine {
+	if limit <= 0 {
+		limit = max(total, 1)
+	}
+	c := &Coroutine{
+		parent: ctx,
+		policy: policy,
+		wg:     chans.NewWaitLimit(limit),
+	}
+	c.ctx, c.cancel = context.WithCancel(ctx)
+	c.Bar = New(
 		WithTotal(int64(total)),
+		WithFormat(
+			WithPlan(),
+			WithRateSize(),
+			WithCustomResult(&c.succeeded, &c.failed),
+			WithSpeed(),
+			WithRemain(),
+		),
+		WithBindField("succeeded", &c.succeeded),
+		WithBindField("failed", &c.failed),
+		WithBindField("retried", &c.retried),
 		WithOption(op...),
 		WithFlush(),
 	)
-	return &Coroutine{
-		Bar: b,
-		wg:  chans.NewWaitLimit(limit),
-	}
+	return c
 }
 
 type Coroutine struct {
 	*Bar
 	wg chans.WaitLimit
+
+	parent    context.Context    //上级context
+	ctx       context.Context    //取消剩余任务
+	cancel    context.CancelFunc //取消函数
+	policy    ErrPolicy          //出错时的处理策略
+	succeeded int64              //成功的数量
+	failed    int64              //失败的数量
+	retried   int64              //重试的次数
+	errs      []error            //所有任务的错误
+	mu        sync.Mutex         //错误锁
 }
 
-func (this *Coroutine) Wait() {
+// Context 任务使用的context,出错取消或者上级取消时结束
+func (this *Coroutine) Context() context.Context {
+	return this.ctx
+}
+
+// Succeeded 成功的任务数量
+func (this *Coroutine) Succeeded() int64 {
+	return atomic.LoadInt64(&this.succeeded)
+}
+
+// Failed 失败的任务数量
+func (this *Coroutine) Failed() int64 {
+	return atomic.LoadInt64(&this.failed)
+}
+
+// Retried 重试的总次数
+func (this *Coroutine) Retried() int64 {
+	return atomic.LoadInt64(&this.retried)
+}
+
+// WithCoroutineRetry 协程模式的重试次数,例 ↻3
+func WithCoroutineRetry(c *Coroutine) Format {
+	return func(b *Bar) string {
+		return fmt.Sprintf("↻%d", c.Retried())
+	}
+}
+
+// Wait 等待所有任务结束,返回所有任务的错误,上级context取消时包含取消的错误,
+// 结束后释放context(同errgroup),之后不能再执行新的任务
+func (this *Coroutine) Wait() error {
 	this.wg.Wait()
+	defer this.cancel()
+
+	this.mu.Lock()
+	errs := append([]error(nil), this.errs...)
+	this.mu.Unlock()
+	if err := this.parent.Err(); err != nil {
+		errs = append(errs, err)
+	}
+
+	//任务被取消,进度条不会走完,需要主动结束,上级取消记为中止,出错取消记为失败
+	if this.ctx.Err() != nil && !this.Bar.Closed() {
+		if this.parent.Err() != nil || len(errs) == 0 {
+			this.Bar.Abort()
+		} else {
+			this.Bar.Fail(errs[0])
+		}
+	}
+
+	return errors.Join(errs...)
 }
 
 func (this *Coroutine) Go(f func()) {
@@ -34,17 +134,65 @@ func (this *Coroutine) GoRetry(f func() error, retry int) {
 	if f == nil {
 		return
 	}
+	this.GoRetryContext(func(ctx context.Context) error {
+		return f()
+	}, retry)
+}
+
+// GoContext 执行任务,任务返回错误时计入失败
+func (this *Coroutine) GoContext(f func(ctx context.Context) error) {
+	this.GoRetryContext(f, 1)
+}
+
+// GoRetryContext 执行任务,失败时立即重试,重试次数用完后计入失败
+func (this *Coroutine) GoRetryContext(f func(ctx context.Context) error, retry int) {
+	this.GoRetryPolicy(f, RetryFixed(max(1, retry), 0))
+}
+
+// GoRetryPolicy 执行任务,失败时按重试策略重试,不能重试后计入失败
+func (this *Coroutine) GoRetryPolicy(f func(ctx context.Context) error, policy *RetryPolicy) {
+	if f == nil || this.ctx.Err() != nil {
+		return
+	}
+	if policy == nil {
+		policy = RetryFixed(1, 0)
+	}
 	this.wg.Add()
 	go func() {
-		defer func() {
-			this.Bar.Add(1)
-			this.Bar.Flush()
-			this.wg.Done()
-		}()
-		for i := 0; i < retry; i++ {
-			if err := f(); err == nil {
-				return
-			}
+		defer this.wg.Done()
+
+		if this.ctx.Err() != nil {
+			//已取消,不计入成功或失败
+			return
+		}
+		err := policy.Do(this.ctx, f, func(n int, err error) {
+			atomic.AddInt64(&this.retried, 1)
+		})
+
+		if err != nil && errors.Is(err, context.Canceled) && this.ctx.Err() != nil {
+			return
 		}
+		this.done(err)
 	}()
 }
+
+// done 记录任务结果,并更新进度条
+func (this *Coroutine) done(err error) {
+	if err == nil {
+		atomic.AddInt64(&this.succeeded, 1)
+	} else {
+		atomic.AddInt64(&this.failed, 1)
+		this.mu.Lock()
+		this.errs = append(this.errs, err)
+		this.mu.Unlock()
+		if this.policy == ErrCancel {
+			this.cancel()
+		}
+	}
+	//最后一个任务结束时,有失败的任务则记为失败
+	if failed := this.Failed(); failed > 0 && !this.Bar.Indeterminate() && this.Succeeded()+failed >= this.Bar.Total() {
+		this.Bar.setState(StateFailed, fmt.Errorf("%d个任务失败", failed))
+	}
+	this.Bar.Add(1)
+	this.Bar.Flush()
+}
@@ -0,0 +1,99 @@
+package bar_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/injoyai/bar"
+	"github.com/injoyai/bar/bartest"
+)
+
+func newCoroutine(ctx context.Context, total, limit int, policy bar.ErrPolicy) (*bar.Coroutine, *bartest.Recorder) {
+	r := bartest.NewRecorder()
+	return bar.NewCoroutineContext(ctx, total, limit, policy, bar.WithWriter(r), bar.WithTTY(), bar.WithRefreshRate(0)), r
+}
+
+// TestCoroutineErrors Wait返回所有任务的错误,有失败的任务时记为失败
+func TestCoroutineErrors(t *testing.T) {
+	c, r := newCoroutine(context.Background(), 4, 2, bar.ErrContinue)
+	e1, e2 := errors.New("错误1"), errors.New("错误2")
+	for _, err := range []error{nil, e1, nil, e2} {
+		c.GoContext(func(ctx context.Context) error { return err })
+	}
+	err := c.Wait()
+	if !errors.Is(err, e1) || !errors.Is(err, e2) {
+		t.Fatalf("wait: %v", err)
+	}
+	if c.Succeeded() != 2 || c.Failed() != 2 || c.State() != bar.StateFailed {
+		t.Fatalf("succeeded %d, failed %d, state %v", c.Succeeded(), c.Failed(), c.State())
+	}
+	if got := r.Last(); !strings.HasSuffix(got, "✗ 2个任务失败") {
+		t.Fatalf("last frame: %q", got)
+	}
+}
+
+// TestCoroutineErrCancel 出错时取消剩余的任务
+func TestCoroutineErrCancel(t *testing.T) {
+	c, _ := newCoroutine(context.Background(), 5, 1, bar.ErrCancel)
+	e := errors.New("错误")
+	var ran int64
+	for i := 0; i < 5; i++ {
+		c.GoContext(func(ctx context.Context) error {
+			atomic.AddInt64(&ran, 1)
+			return e
+		})
+	}
+	err := c.Wait()
+	if !errors.Is(err, e) || errors.Is(err, context.Canceled) {
+		t.Fatalf("wait: %v", err)
+	}
+	if ran != 1 || c.Failed() != 1 || c.State() != bar.StateFailed {
+		t.Fatalf("ran %d, failed %d, state %v", ran, c.Failed(), c.State())
+	}
+}
+
+// TestCoroutineParentCancel 上级context取消时,进行中的任务收到取消,未开始的任务不再执行,记为中止
+func TestCoroutineParentCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c, _ := newCoroutine(ctx, 3, 1, bar.ErrContinue)
+	started := make(chan struct{})
+	c.GoContext(func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	<-started
+	cancel()
+	var ran int64
+	c.GoContext(func(ctx context.Context) error {
+		atomic.AddInt64(&ran, 1)
+		return nil
+	})
+	err := c.Wait()
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("wait: %v", err)
+	}
+	if ran != 0 || c.Succeeded() != 0 || c.Failed() != 0 || c.State() != bar.StateAborted {
+		t.Fatalf("ran %d, succeeded %d, failed %d, state %v", ran, c.Succeeded(), c.Failed(), c.State())
+	}
+}
+
+// TestCoroutineRelease Wait之后释放context,不会一直挂在上级context上
+func TestCoroutineRelease(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c, _ := newCoroutine(ctx, 1, 1, bar.ErrContinue)
+	c.Go(func() {})
+	if err := c.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if c.Context().Err() == nil {
+		t.Fatal("context not released after Wait")
+	}
+	if c.State() != bar.StateSucceeded {
+		t.Fatalf("state: %v", c.State())
+	}
+}
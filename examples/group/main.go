@@ -0,0 +1,25 @@
+package main
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/injoyai/bar"
+)
+
+func main() {
+	g := bar.NewGroup(bar.WithGroupPin())
+	for i := 0; i < 5; i++ {
+		b := g.New(
+			bar.WithTotal(100),
+			bar.WithPrefix("任务"+string(rune('A'+i))+" "),
+		)
+		go func() {
+			for !b.Closed() {
+				time.Sleep(time.Millisecond * time.Duration(20+rand.Intn(80)))
+				b.Add(1).Flush()
+			}
+		}()
+	}
+	g.Wait()
+}
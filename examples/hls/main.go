@@ -4,5 +4,10 @@ import "github.com/injoyai/bar"
 
 func main() {
 	s := "http://devimages.apple.com.edgekey.net/streaming/examples/bipbop_4x3/gear2/prog_index.m3u8"
-	bar.DownloadHLS(s, "./data")
+	if err := bar.DownloadHLS(s, "./data"); err != nil {
+		panic(err)
+	}
+	if err := bar.MergeHLS("./data", "./output.ts", bar.WithMergeDelete()); err != nil {
+		panic(err)
+	}
 }
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"time"
+
+	"github.com/injoyai/bar"
+)
+
+func main() {
+	//总数量未知,进度条来回滚动
+	b := bar.New()
+	for i := 0; i < 100; i++ {
+		b.Add(1)
+		b.Flush()
+		time.Sleep(time.Millisecond * 50)
+	}
+	//得知总数量后切换成正常模式
+	b.SetTotal(200)
+	for i := 0; i < 100; i++ {
+		b.Add(1)
+		b.Flush()
+		time.Sleep(time.Millisecond * 50)
+	}
+}
@@ -3,18 +3,20 @@ package bar
 import (
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/injoyai/bar/internal/volume"
-	"github.com/injoyai/base/maps"
 	"github.com/injoyai/conv"
 )
 
 // WithPlan 进度条,例 [>>>   ]
+// 不确定模式(总数量未知)时显示来回移动的滑块,例 [   ###    ]
+// 设置 SetFlex 后宽度填满行内剩余的空间
 func WithPlan(op ...PlanOption) Format {
 	p := NewPlan(op...)
 	return func(b *Bar) string {
-		return p.String(b.Rate())
+		return p.render(b)
 	}
 }
 
@@ -28,21 +30,21 @@ func WithText(a ...any) Format {
 // WithTime 时间
 func WithTime() Format {
 	return func(b *Bar) string {
-		return time.Now().Format(time.TimeOnly)
+		return b.Now().Format(time.TimeOnly)
 	}
 }
 
 // WithDate 日期
 func WithDate() Format {
 	return func(b *Bar) string {
-		return time.Now().Format(time.DateOnly)
+		return b.Now().Format(time.DateOnly)
 	}
 }
 
 // WithDateTime 日期时间
 func WithDateTime() Format {
 	return func(b *Bar) string {
-		return time.Now().Format(time.DateTime)
+		return b.Now().Format(time.DateTime)
 	}
 }
 
@@ -66,6 +68,9 @@ func WithAnimationMoon() Format {
 // WithRate 进度百分比,例 58%
 func WithRate() Format {
 	return func(b *Bar) string {
+		if b.Indeterminate() {
+			return "-"
+		}
 		return fmt.Sprintf("%0.1f%%", float64(b.Current())*100/float64(b.Total()))
 	}
 }
@@ -73,6 +78,9 @@ func WithRate() Format {
 // WithRateSize //进度数量,例 58/100
 func WithRateSize() Format {
 	return func(b *Bar) string {
+		if b.Indeterminate() {
+			return fmt.Sprintf("%d/-", b.Current())
+		}
 		return fmt.Sprintf("%d/%d", b.Current(), b.Total())
 	}
 }
@@ -81,42 +89,73 @@ func WithRateSize() Format {
 func WithRateSizeUnit() Format {
 	return func(b *Bar) string {
 		currentNum, currentUnit := volume.SizeUnit(b.Current())
+		if b.Indeterminate() {
+			return fmt.Sprintf("%0.1f%s/-", currentNum, currentUnit)
+		}
 		totalNum, totalUnit := volume.SizeUnit(b.Total())
 		return fmt.Sprintf("%0.1f%s/%0.1f%s", currentNum, currentUnit, totalNum, totalUnit)
 	}
 }
 
-func speed(cache *maps.Safe, key string, size int64, expiration time.Duration, f func(float64) string) string {
+// speedCache 速度的缓存,按进度条的耗时过期,不受系统时间影响
+type speedCache struct {
+	mu          sync.Mutex
+	inited      bool          //是否已经记录了第一次的数量和时间
+	lastCurrent int64         //上次计算时的数量
+	lastTime    time.Duration //上次计算时的耗时
+	value       string        //缓存的速度
+	until       time.Duration //缓存的过期耗时
+}
 
-	timeKey := "time_" + key
-	cacheKey := "speed_" + key
-	//最后的数据时间
-	lastTime, _ := cache.GetOrSetByHandler(timeKey, func() (any, error) {
-		return time.Time{}, nil
-	})
+// speed 计算速度,current为当前数量,now为不包括暂停的耗时,暂停的时间不计入速度,
+// 速度为两次计算之间数量的变化除以耗时的变化,和每次增加多少,两帧之间增加了几次无关,
+// 第一次调用只记录数量和时间,速度为0,避免用开始以来的全部耗时计算出错误的速度
+func (this *speedCache) speed(current int64, now, expiration time.Duration, f func(float64) string) string {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+
+	if !this.inited {
+		this.inited = true
+		this.lastCurrent = current
+		this.lastTime = now
+		return f(0)
+	}
 
-	//记录这次时间,用于下次计算时间差
-	now := time.Now()
-	cache.Set(timeKey, now)
+	//尝试从缓存获取速度,存在则直接返回,由expiration控制,
+	//缓存期间不更新记录的数量和时间,下次计算的是整个缓存期间的平均速度
+	if this.value != "" && now < this.until {
+		return this.value
+	}
 
-	//尝试从缓存获取速度,存在则直接返回,由expiration控制
-	if val, ok := cache.Get(cacheKey); ok {
-		return val.(string)
+	//时间没有流逝,无法计算速度
+	dt := now - this.lastTime
+	if dt <= 0 {
+		if this.value != "" {
+			return this.value
+		}
+		return f(0)
 	}
 
-	//计算速度
-	size = conv.Select(size >= 0, size, 0)
-	spendSize := float64(size) / now.Sub(lastTime.(time.Time)).Seconds()
-	s := f(spendSize)
-	cache.Set(cacheKey, s, expiration)
-	return s
+	//计算速度,数量减少(例如Seek到前面)时记为0
+	size := max(current-this.lastCurrent, 0)
+	this.lastCurrent = current
+	this.lastTime = now
+	this.value = f(float64(size) / dt.Seconds())
+	this.until = now + expiration
+	return this.value
 }
 
+// Paused 暂停时速度的显示内容
+const Paused = "暂停"
+
 // WithSpeed //进度速度,例 13/s
 func WithSpeed(expiration ...time.Duration) Format {
-	cache := maps.NewSafe()
+	cache := &speedCache{}
 	return func(b *Bar) string {
-		return speed(cache, "Speed", b.Last(), conv.Default(time.Millisecond*500, expiration...), func(size float64) string {
+		if b.Paused() {
+			return Paused
+		}
+		return cache.speed(b.Current(), b.Elapsed(), conv.Default(time.Millisecond*500, expiration...), func(size float64) string {
 			return fmt.Sprintf("%0.1f/s", size)
 		})
 	}
@@ -124,19 +163,34 @@ func WithSpeed(expiration ...time.Duration) Format {
 
 // WithSpeedUnit //进度速度带单位,例 13MB/s
 func WithSpeedUnit(expiration ...time.Duration) Format {
-	cache := maps.NewSafe()
+	cache := &speedCache{}
 	return func(b *Bar) string {
-		return speed(cache, "SpeedUnit", b.Last(), conv.Default(time.Millisecond*500, expiration...), func(size float64) string {
+		if b.Paused() {
+			return Paused
+		}
+		return cache.speed(b.Current(), b.Elapsed(), conv.Default(time.Millisecond*500, expiration...), func(size float64) string {
 			f, unit := volume.SizeUnit(int64(size))
 			return fmt.Sprintf("%0.1f%s/s", f, unit)
 		})
 	}
 }
 
+// avgSpeed 平均速度,还没有耗时(例如测试时钟的开始时刻)时为0
+func avgSpeed(b *Bar) float64 {
+	elapsed := b.Elapsed()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(b.Current()) / elapsed.Seconds()
+}
+
 // WithSpeedAvg //进度平均速度,例 13/s
 func WithSpeedAvg() Format {
 	return func(b *Bar) string {
-		speedSize := float64(b.Current()) / time.Since(b.StartTime()).Seconds()
+		if b.Paused() {
+			return Paused
+		}
+		speedSize := avgSpeed(b)
 		return fmt.Sprintf("%0.1f/s", speedSize)
 	}
 }
@@ -144,7 +198,10 @@ func WithSpeedAvg() Format {
 // WithSpeedUnitAvg //进度平均速度带单位,例 13MB/s
 func WithSpeedUnitAvg() Format {
 	return func(b *Bar) string {
-		speedSize := float64(b.Current()) / time.Since(b.StartTime()).Seconds()
+		if b.Paused() {
+			return Paused
+		}
+		speedSize := avgSpeed(b)
 		f, unit := volume.SizeUnit(int64(speedSize))
 		return fmt.Sprintf("%0.1f%s/s", f, unit)
 	}
@@ -153,22 +210,25 @@ func WithSpeedUnitAvg() Format {
 // WithUsed 已经耗时,例 2m20s
 func WithUsed() Format {
 	return func(b *Bar) string {
-		return time.Since(b.StartTime()).String()
+		return b.Elapsed().String()
 	}
 }
 
 // WithUsedSecond 已经耗时,例 600s
 func WithUsedSecond() Format {
 	return func(b *Bar) string {
-		return fmt.Sprintf("%0.1fs", time.Since(b.StartTime()).Seconds())
+		return fmt.Sprintf("%0.1fs", b.Elapsed().Seconds())
 	}
 }
 
 // WithRemain 预计剩余时间(根据所有数据来计算) 例 1m18s
 func WithRemain() Format {
 	return func(b *Bar) string {
+		if b.Indeterminate() {
+			return "-"
+		}
 		rate := float64(b.Current()) / float64(b.Total())
-		spend := time.Since(b.StartTime())
+		spend := b.Elapsed()
 		remain := "-"
 		if rate > 0 {
 			sub := time.Duration(float64(spend)/rate - float64(spend))
@@ -183,7 +243,7 @@ func WithRemain() Format {
 func WithRemainInterval(n ...int) Format {
 	type node struct {
 		current int64
-		time    time.Time
+		time    time.Duration //不包括暂停的耗时
 	}
 
 	var (
@@ -209,7 +269,7 @@ func WithRemainInterval(n ...int) Format {
 		buf = make([]node, capacity)
 	}
 
-	push := func(cur int64, t time.Time) {
+	push := func(cur int64, t time.Duration) {
 		// 写入环形缓冲区尾部
 		tail := (head + size) % capacity
 		buf[tail] = node{current: cur, time: t}
@@ -228,12 +288,12 @@ func WithRemainInterval(n ...int) Format {
 			b.OnSet(func(b *Bar) {
 				mu.Lock()
 				defer mu.Unlock()
-				push(b.Current(), b.LastTime())
+				push(b.Current(), b.Elapsed())
 			})
 			hooked = true
 		}
 
-		if size == 0 {
+		if size == 0 || b.Indeterminate() {
 			mu.Unlock()
 			return "-"
 		}
@@ -242,7 +302,7 @@ func WithRemainInterval(n ...int) Format {
 		mu.Unlock()
 
 		subCurrent := end.current - start.current
-		subTime := end.time.Sub(start.time)
+		subTime := end.time - start.time
 
 		// 需要同时满足：有数据进展 + 时间有流逝，避免除零或负值
 		if subCurrent <= 0 || subTime <= 0 {
@@ -272,3 +332,25 @@ func WithCustomRateSizeUnit(size, total *int64) Format {
 		return fmt.Sprintf("%s/%s", volume.SizeString(*size), volume.SizeString(*total))
 	}
 }
+
+// WithCustomDuration 时长,例 1m30s,需传指针(纳秒),不然不会变
+func WithCustomDuration(duration *int64) Format {
+	return func(b *Bar) string {
+		d := time.Duration(atomic.LoadInt64(duration))
+		return (d - d%time.Second).String()
+	}
+}
+
+// WithCustomResult 成功和失败的数量,例 ✓58 ✗2,需传指针,不然不会变
+func WithCustomResult(succeeded, failed *int64) Format {
+	return func(b *Bar) string {
+		return fmt.Sprintf("✓%d ✗%d", atomic.LoadInt64(succeeded), atomic.LoadInt64(failed))
+	}
+}
+
+// WithCustomRetry 重试次数,例 ↻3,需传指针,不然不会变,协程模式的重试次数使用 WithCoroutineRetry
+func WithCustomRetry(retried *int64) Format {
+	return func(b *Bar) string {
+		return fmt.Sprintf("↻%d", atomic.LoadInt64(retried))
+	}
+}
@@ -0,0 +1,129 @@
+package bar
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/injoyai/bar/internal/volume"
+	"github.com/injoyai/conv"
+)
+
+// DefaultHalfLife 指数加权移动平均默认的半衰期
+const DefaultHalfLife = time.Second * 5
+
+// newEWMA 新建指数加权移动平均的速度,半衰期越短对变化越敏感,越长越平滑
+func newEWMA(halfLife time.Duration) *ewma {
+	if halfLife <= 0 {
+		halfLife = DefaultHalfLife
+	}
+	return &ewma{halfLife: halfLife}
+}
+
+// ewma 指数加权移动平均的速度,由每次数量变化驱动,与刷新频率无关
+type ewma struct {
+	mu       sync.Mutex
+	halfLife time.Duration //半衰期
+	hooked   bool          //是否已注册设置事件
+	inited   bool          //是否有首个采样
+	rate     float64       //速度(每秒)
+	current  int64         //上次采样的数量
+	time     time.Duration //上次采样的时间(不包括暂停的耗时)
+}
+
+// hook 首次使用时注册设置事件,每次数量变化时采样
+func (this *ewma) hook(b *Bar) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	if this.hooked {
+		return
+	}
+	this.hooked = true
+	this.current = b.Current()
+	this.time = b.Elapsed()
+	b.OnChange(func(b *Bar) {
+		this.update(b.Current(), b.Elapsed())
+	})
+}
+
+// update 采样,间隔太短的变化会累计到下次采样
+func (this *ewma) update(current int64, now time.Duration) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	dt := now - this.time
+	if dt < time.Millisecond {
+		return
+	}
+	instant := float64(current-this.current) / dt.Seconds()
+	if !this.inited {
+		this.rate = instant
+		this.inited = true
+	} else {
+		alpha := 1 - math.Exp(-dt.Seconds()*math.Ln2/this.halfLife.Seconds())
+		this.rate += alpha * (instant - this.rate)
+	}
+	this.current = current
+	this.time = now
+}
+
+// speed 当前的速度,长时间没有变化时速度会按半衰期衰减,暂停期间不衰减
+func (this *ewma) speed(now time.Duration) float64 {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	if !this.inited {
+		return 0
+	}
+	idle := now - this.time
+	if idle <= 0 {
+		return this.rate
+	}
+	return this.rate * math.Exp(-idle.Seconds()*math.Ln2/this.halfLife.Seconds())
+}
+
+// WithSpeedEWMA 进度速度(指数加权移动平均),例 13/s
+// halfLife 半衰期,默认 DefaultHalfLife ,每次数量变化都会参与计算,不受刷新频率影响
+func WithSpeedEWMA(halfLife ...time.Duration) Format {
+	e := newEWMA(conv.Default(DefaultHalfLife, halfLife...))
+	return func(b *Bar) string {
+		e.hook(b)
+		if b.Paused() {
+			return Paused
+		}
+		return fmt.Sprintf("%0.1f/s", e.speed(b.Elapsed()))
+	}
+}
+
+// WithSpeedUnitEWMA 进度速度带单位(指数加权移动平均),例 13MB/s
+func WithSpeedUnitEWMA(halfLife ...time.Duration) Format {
+	e := newEWMA(conv.Default(DefaultHalfLife, halfLife...))
+	return func(b *Bar) string {
+		e.hook(b)
+		if b.Paused() {
+			return Paused
+		}
+		f, unit := volume.SizeUnit(int64(e.speed(b.Elapsed())))
+		return fmt.Sprintf("%0.1f%s/s", f, unit)
+	}
+}
+
+// WithRemainEWMA 预计剩余时间(根据指数加权移动平均的速度计算),例 1m18s
+func WithRemainEWMA(halfLife ...time.Duration) Format {
+	e := newEWMA(conv.Default(DefaultHalfLife, halfLife...))
+	return func(b *Bar) string {
+		e.hook(b)
+		if b.Indeterminate() {
+			return "-"
+		}
+		remain := b.Total() - b.Current()
+		if remain <= 0 {
+			return "0s"
+		}
+		speed := e.speed(b.Elapsed())
+		if speed <= 0 {
+			return "-"
+		}
+		sub := time.Duration(float64(remain) / speed * float64(time.Second))
+		return (sub - sub%time.Second).String()
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/fatih/color"
+	"github.com/injoyai/bar/internal/terminal"
 	"github.com/injoyai/conv"
 )
 
@@ -23,6 +24,11 @@ func NewPlan(op ...PlanOption) *Plan {
 		padding: DefaultPadding,
 		color:   nil,
 		width:   50,
+
+		pausedText:  Paused,
+		pausedColor: color.New(color.FgYellow),
+		finishColor: color.New(color.FgGreen),
+		failColor:   color.New(color.FgRed),
 	}
 	for _, o := range op {
 		o(p)
@@ -37,6 +43,14 @@ type Plan struct {
 	padding string       //填充 例 .
 	color   *color.Color //整体颜色
 	width   int          //宽度
+	flex    bool         //弹性宽度,填满其它格式剩余的宽度
+	frame   int          //不确定模式的帧序号
+	label   string       //显示在进度条中间的文字,仅在渲染时有效
+
+	pausedText  string       //暂停时显示在进度条中间的文字,不依赖颜色(纯文本模式或者关闭颜色时也能看出暂停)
+	pausedColor *color.Color //暂停时的颜色
+	finishColor *color.Color //成功结束时的颜色
+	failColor   *color.Color //失败或中止时的颜色
 }
 
 func (this *Plan) SetPrefix(prefix string) {
@@ -59,17 +73,48 @@ func (this *Plan) SetWidth(width int) {
 	this.width = width
 }
 
+// SetFlex 设置弹性宽度,填满行宽(终端宽度)减去其它格式后剩余的宽度,
+// 不限制行宽时使用 SetWidth 设置的宽度
+func (this *Plan) SetFlex(flex ...bool) {
+	this.flex = len(flex) == 0 || flex[0]
+}
+
 func (this *Plan) SetColor(a color.Attribute) {
 	this.color = color.New(a)
 }
 
+// SetPausedText 设置暂停时显示在进度条中间的文字,默认 Paused ,为空表示不显示
+func (this *Plan) SetPausedText(text string) {
+	this.pausedText = text
+}
+
+// SetPausedColor 设置暂停时的颜色
+func (this *Plan) SetPausedColor(a color.Attribute) {
+	this.pausedColor = color.New(a)
+}
+
+// SetFinishColor 设置成功结束时的颜色
+func (this *Plan) SetFinishColor(a color.Attribute) {
+	this.finishColor = color.New(a)
+}
+
+// SetFailColor 设置失败或中止时的颜色
+func (this *Plan) SetFailColor(a color.Attribute) {
+	this.failColor = color.New(a)
+}
+
 func (this *Plan) String(rate float64) string {
+	return this.string(rate, this.width)
+}
+
+// string 按指定宽度渲染进度条
+func (this *Plan) string(rate float64, width int) string {
 
 	//归一化
 	rate = conv.Range(rate, 0, 1)
 
 	//进度条的数量
-	count := int(float64(this.width) * rate)
+	count := int(float64(width) * rate)
 	if count < 0 {
 		count = 0
 	}
@@ -94,14 +139,148 @@ func (this *Plan) String(rate float64) string {
 		lenPadding = 1
 	}
 	// 补全剩余部分（未完成区域）
-	for i := count; i < this.width; i++ {
+	for i := count; i < width; i++ {
 		b.WriteRune(paddingRunes[i%lenPadding])
 	}
 
-	barStr := fmt.Sprintf("%s%s%s", this.prefix, b.String(), this.suffix)
+	barStr := fmt.Sprintf("%s%s%s", this.prefix, overlay(b.String(), this.label), this.suffix)
 
 	if this.color != nil {
 		barStr = this.color.Sprint(barStr)
 	}
 	return barStr
 }
+
+// Marquee 不确定模式的进度条,滑块在两端之间来回移动,frame为帧序号
+func (this *Plan) Marquee(frame int) string {
+	return this.marquee(frame, this.width)
+}
+
+// marquee 按指定宽度渲染不确定模式的进度条
+func (this *Plan) marquee(frame, width int) string {
+
+	//滑块的宽度
+	size := width / 5
+	if size < 1 {
+		size = 1
+	}
+	if size > width {
+		size = width
+	}
+
+	//滑块的位置,来回移动
+	pos := 0
+	if span := width - size; span > 0 {
+		pos = frame % (span * 2)
+		if pos < 0 {
+			pos = -pos
+		}
+		if pos > span {
+			pos = span*2 - pos
+		}
+	}
+
+	styleRunes := []rune(this.style)
+	if len(styleRunes) == 0 {
+		styleRunes = []rune(DefaultStyle)
+	}
+	paddingRunes := []rune(this.padding)
+	if len(paddingRunes) == 0 {
+		paddingRunes = []rune(DefaultPadding)
+	}
+
+	var b strings.Builder
+	for i := 0; i < width; i++ {
+		if i >= pos && i < pos+size {
+			b.WriteRune(styleRunes[(i-pos)%len(styleRunes)])
+		} else {
+			b.WriteRune(paddingRunes[i%len(paddingRunes)])
+		}
+	}
+
+	barStr := fmt.Sprintf("%s%s%s", this.prefix, overlay(b.String(), this.label), this.suffix)
+
+	if this.color != nil {
+		barStr = this.color.Sprint(barStr)
+	}
+	return barStr
+}
+
+// render 渲染进度条的状态,弹性宽度时根据行宽计算宽度
+func (this *Plan) render(b *Bar) string {
+	width := this.width
+	if this.flex {
+		switch {
+		case b.flex == 0:
+			//测量其它格式的宽度,标记为弹性格式
+			b.flexing = true
+			return ""
+		case b.flex > 0:
+			width = max(b.flex-terminal.Width(this.prefix)-terminal.Width(this.suffix), 1)
+		}
+	}
+	if c, ok := this.stateColor(b); ok {
+		//暂停或结束时使用对应状态的颜色,不确定模式的滑块停止移动,暂停时中间显示暂停的文字
+		old := this.color
+		this.color = c
+		if b.State() == StateRunning {
+			this.label = this.pausedText
+		}
+		defer func() { this.color, this.label = old, "" }()
+		if b.Indeterminate() {
+			return this.marquee(this.frame, width)
+		}
+	}
+	if b.Indeterminate() {
+		this.frame++
+		return this.marquee(this.frame, width)
+	}
+	return this.string(b.Rate(), width)
+}
+
+// stateColor 暂停或结束状态对应的颜色,运行中返回false
+func (this *Plan) stateColor(b *Bar) (*color.Color, bool) {
+	switch b.State() {
+	case StateSucceeded:
+		return this.finishColor, true
+	case StateFailed, StateAborted:
+		return this.failColor, true
+	}
+	if b.Paused() {
+		return this.pausedColor, true
+	}
+	return nil, false
+}
+
+// overlay 把文字覆盖在进度条的中间,进度条宽度不够时不覆盖
+func overlay(s, label string) string {
+	lw := terminal.Width(label)
+	sw := terminal.Width(s)
+	if lw == 0 || sw < lw+2 {
+		return s
+	}
+	start := (sw - lw) / 2
+	end := start + lw
+	var b strings.Builder
+	n := 0
+	written := false
+	for _, r := range s {
+		w := terminal.Width(string(r))
+		switch {
+		case n+w <= start || n >= end:
+			b.WriteRune(r)
+		default:
+			//被文字覆盖的部分,宽字符只覆盖了一半时用空格补齐
+			if !written {
+				b.WriteString(strings.Repeat(" ", max(start-n, 0)))
+				b.WriteString(label)
+				written = true
+			}
+			if n+w > end {
+				b.WriteString(strings.Repeat(" ", n+w-end))
+			}
+		}
+		n += w
+	}
+	return b.String()
+}
@@ -0,0 +1,181 @@
+package bar
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/injoyai/bar/internal/volume"
+)
+
+var (
+	// templateFormats 模板中可以使用的格式,每个模板都会重新生成,避免有状态的格式(例如速度)互相影响
+	templateFormats = map[string]func() Format{
+		"percent":        WithRate,
+		"rate":           WithRate,
+		"rateSize":       WithRateSize,
+		"rateSizeUnit":   WithRateSizeUnit,
+		"speed":          func() Format { return WithSpeed() },
+		"speedUnit":      func() Format { return WithSpeedUnit() },
+		"speedAvg":       WithSpeedAvg,
+		"speedUnitAvg":   WithSpeedUnitAvg,
+		"used":           WithUsed,
+		"usedSecond":     WithUsedSecond,
+		"eta":            WithRemain,
+		"remain":         WithRemain,
+		"remainInterval": func() Format { return WithRemainInterval() },
+		"speedEWMA":      func() Format { return WithSpeedEWMA() },
+		"speedUnitEWMA":  func() Format { return WithSpeedUnitEWMA() },
+		"remainEWMA":     func() Format { return WithRemainEWMA() },
+		"time":           WithTime,
+		"date":           WithDate,
+		"datetime":       WithDateTime,
+		"snake":          WithAnimationSnake,
+		"moon":           WithAnimationMoon,
+	}
+	templateFormatsMu sync.RWMutex
+)
+
+// RegisterFormat 注册模板中可以使用的格式,例 RegisterFormat("name", func() Format { return WithText("name") })
+// 模板中使用 {{name}} ,每个模板会调用f生成独立的Format
+func RegisterFormat(name string, f func() Format) {
+	templateFormatsMu.Lock()
+	defer templateFormatsMu.Unlock()
+	templateFormats[name] = f
+}
+
+// WithTemplate 模板样式,基于text/template,解析失败时显示错误信息
+// 例 "{{.Prefix}} {{bar 40}} {{percent}} {{bytes .Current}}/{{bytes .Total}} {{speed}} eta {{eta}}"
+// 数据为*Bar,可以使用 .Current .Total .Prefix .Suffix 等,
+// 模板中使用了 .Prefix 或 .Suffix 时,前缀和后缀(WithPrefix/WithSuffix)由模板决定位置,不再自动加上,
+// 函数为所有注册的格式,另外还有:
+// {{bar}} {{bar 40}} 进度条,可指定宽度; {{animation 11}} 动画,参数为 Animations 的序号; {{bytes 1024}} 字节大小
+func WithTemplate(text string) Format {
+	f, err := ParseTemplate(text)
+	if err != nil {
+		return WithText(err)
+	}
+	return f
+}
+
+// ParseTemplate 解析模板样式,见 WithTemplate
+func ParseTemplate(text string) (Format, error) {
+	var (
+		mu  sync.Mutex
+		cur *Bar
+		buf strings.Builder
+	)
+
+	//带参数的格式,按参数缓存
+	plans := map[int]Format{}
+	animations := map[int]Format{}
+
+	funcs := template.FuncMap{
+		"bar": func(width ...int) string {
+			w := 0
+			if len(width) > 0 {
+				w = width[0]
+			}
+			f, ok := plans[w]
+			if !ok {
+				f = WithPlan(func(p *Plan) {
+					if w > 0 {
+						p.SetWidth(w)
+					}
+				})
+				plans[w] = f
+			}
+			return f(cur)
+		},
+		"animation": func(n int) string {
+			f, ok := animations[n]
+			if !ok {
+				ls := Animations[n]
+				if len(ls) == 0 {
+					ls = []string{""}
+				}
+				f = WithAnimation(ls)
+				animations[n] = f
+			}
+			return f(cur)
+		},
+		"bytes": func(n int64) string {
+			return volume.SizeString(n)
+		},
+	}
+	funcs["plan"] = funcs["bar"]
+
+	templateFormatsMu.RLock()
+	for k, v := range templateFormats {
+		f := v()
+		funcs[k] = func() string { return f(cur) }
+	}
+	templateFormatsMu.RUnlock()
+
+	tpl, err := template.New("bar").Funcs(funcs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	affix := usesField(tpl.Tree.Root, "Prefix", "Suffix")
+
+	return func(b *Bar) string {
+		mu.Lock()
+		defer mu.Unlock()
+		if affix {
+			b.ownAffix = true
+		}
+		cur = b
+		buf.Reset()
+		if err := tpl.Execute(&buf, b); err != nil {
+			return err.Error()
+		}
+		return buf.String()
+	}, nil
+}
+
+// usesField 模板中是否使用了字段,例 .Prefix
+func usesField(node parse.Node, names ...string) bool {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return false
+		}
+		for _, v := range n.Nodes {
+			if usesField(v, names...) {
+				return true
+			}
+		}
+	case *parse.ActionNode:
+		return usesField(n.Pipe, names...)
+	case *parse.PipeNode:
+		if n == nil {
+			return false
+		}
+		for _, v := range n.Cmds {
+			if usesField(v, names...) {
+				return true
+			}
+		}
+	case *parse.CommandNode:
+		for _, v := range n.Args {
+			if usesField(v, names...) {
+				return true
+			}
+		}
+	case *parse.FieldNode:
+		return len(n.Ident) > 0 && slices.Contains(names, n.Ident[0])
+	case *parse.IfNode:
+		return usesField(&n.BranchNode, names...)
+	case *parse.RangeNode:
+		return usesField(&n.BranchNode, names...)
+	case *parse.WithNode:
+		return usesField(&n.BranchNode, names...)
+	case *parse.BranchNode:
+		return usesField(n.Pipe, names...) || usesField(n.List, names...) || usesField(n.ElseList, names...)
+	case *parse.TemplateNode:
+		return usesField(n.Pipe, names...)
+	}
+	return false
+}
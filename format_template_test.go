@@ -0,0 +1,38 @@
+package bar_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/injoyai/bar"
+	"github.com/injoyai/bar/bartest"
+)
+
+// TestTemplatePrefix 模板中使用了 .Prefix 或 .Suffix 时,不再自动加上前缀和后缀
+func TestTemplatePrefix(t *testing.T) {
+	for _, v := range []struct {
+		text string
+		want string
+	}{
+		{
+			"{{.Prefix}} {{bar 40}} {{percent}} {{bytes .Current}}/{{bytes .Total}} {{speed}} eta {{eta}}",
+			"下载 [####################                    ] 50.0% 512.0B/1.0KB 256.0/s eta 2s",
+		},
+		{"{{percent}}", "下载50.0%.ts"},
+		{"{{if .Prefix}}<{{.Prefix}}>{{end}} {{percent}}", "<下载> 50.0%"},
+		{"{{percent}} {{with .Suffix}}{{.}}{{end}}", "50.0% .ts"},
+	} {
+		b, c, r := bartest.New(
+			bar.WithTotal(1024),
+			bar.WithPrefix("下载"),
+			bar.WithSuffix(".ts"),
+			bar.WithFormat(bar.WithTemplate(v.text)),
+		)
+		b.Flush()
+		c.Add(2 * time.Second)
+		b.Add(512).Flush()
+		if got := r.Last(); got != v.want {
+			t.Fatalf("%s: got %q, want %q", v.text, got, v.want)
+		}
+	}
+}
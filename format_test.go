@@ -0,0 +1,45 @@
+package bar_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/injoyai/bar"
+	"github.com/injoyai/bar/bartest"
+)
+
+func TestSpeedFirstFrame(t *testing.T) {
+	b, c, r := bartest.New(
+		bar.WithTotal(100),
+		bar.WithFormat(bar.WithSpeed()),
+	)
+	c.Add(10 * time.Second)
+	b.Add(3).Flush()
+	if got := r.Last(); got != "0.0/s" {
+		t.Fatalf("first frame: got %q, want %q", got, "0.0/s")
+	}
+	c.Add(time.Second)
+	b.Add(3).Flush()
+	if got := r.Last(); got != "3.0/s" {
+		t.Fatalf("second frame: got %q, want %q", got, "3.0/s")
+	}
+}
+
+// TestSpeedManyAdds 两帧之间多次增加,速度按数量的变化计算,而不是最后一次增加的值
+func TestSpeedManyAdds(t *testing.T) {
+	b, c, r := bartest.New(
+		bar.WithTotal(100000),
+		bar.WithFormat(bar.WithSpeed(), bar.WithSpeedUnit()),
+	)
+	b.Flush()
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 1000; j++ {
+			c.Add(time.Millisecond)
+			b.Add(4)
+		}
+		b.Flush()
+		if got, want := r.Last(), "4000.0/s  3.9KB/s"; got != want {
+			t.Fatalf("frame %d: got %q, want %q", i, got, want)
+		}
+	}
+}
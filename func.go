@@ -1,11 +1,10 @@
 package bar
 
 import (
+	"context"
 	"io"
-	"net/url"
 	"os"
 	"path/filepath"
-	"strings"
 	"sync/atomic"
 	"time"
 
@@ -13,12 +12,27 @@ import (
 	"github.com/injoyai/bar/internal/util"
 )
 
+// Copy 复制数据并显示进度,读取完成时成功结束,出错时失败结束,
+// total<=0 表示总数量未知(不确定模式),完成后以实际大小作为总数量
 func Copy(w io.Writer, r io.Reader, total int64) (int64, error) {
-	return New(WithTotal(total)).Copy(w, r)
+	b := New(WithTotal(total))
+	n, err := b.Copy(w, r)
+	if err != nil {
+		b.Fail(err)
+	} else {
+		b.Finish()
+	}
+	return n, err
 }
 
+// Download 下载文件,显示带单位的进度
 func Download(url, filename string, proxy ...string) (int64, error) {
-	return New().Download(url, filename, proxy...)
+	return New(WithFormatDefaultUnit()).Download(url, filename, proxy...)
+}
+
+// DownloadParallel 多连接分段下载文件,显示带单位的进度,n为分段数量
+func DownloadParallel(url, filename string, n int, proxy ...string) (int64, error) {
+	return New(WithFormatDefaultUnit()).DownloadParallel(url, filename, n, proxy...)
 }
 
 func DownloadHLS(source, dir string, op ...HLSOption) error {
@@ -34,23 +48,37 @@ func DownloadHLS(source, dir string, op ...HLSOption) error {
 		v(cfg)
 	}
 
+	if cfg.RetryPolicy == nil {
+		cfg.RetryPolicy = RetryFixed(max(1, cfg.Retry), time.Second*5)
+	}
+
 	os.MkdirAll(dir, os.ModePerm)
 
-	ls, err := m3u8.Decode(source)
+	if cfg.Live {
+		return downloadHLSLive(source, dir, cfg)
+	}
+
+	ls, err := m3u8.DecodeSegments(source, m3u8.Select(cfg.Variant))
 	if err != nil {
 		return err
 	}
 
+	//清单记录播放列表的顺序和本地文件名,合并时使用
+	manifest := newHLSManifest(source, ls)
+	if err := manifest.Save(filepath.Join(dir, HLSManifestName)); err != nil {
+		return err
+	}
+
 	current := int64(0)
 	total := int64(0)
 	index := int64(0)
-	b := NewCoroutine(len(ls), cfg.Coroutine,
-		WithFormat(
-			WithPlan(),
-			WithRateSize(),
-			WithCustomRateSizeUnit(&current, &total),
-			WithRemainInterval(),
-		),
+	b := NewCoroutine(len(ls), cfg.Coroutine)
+	b.SetFormat(
+		WithPlan(),
+		WithRateSize(),
+		WithCustomRateSizeUnit(&current, &total),
+		WithCoroutineRetry(b),
+		WithRemainInterval(),
 	)
 
 	h := util.NewClient().SetTimeout(0).SetKeepAlive()
@@ -58,6 +86,8 @@ func DownloadHLS(source, dir string, op ...HLSOption) error {
 		return err
 	}
 
+	c := &hlsClient{client: h}
+
 	f := func(u string, n int64, log bool) {
 		idx := atomic.AddInt64(&index, 1)
 		cur := atomic.AddInt64(&current, n)
@@ -70,50 +100,22 @@ func DownloadHLS(source, dir string, op ...HLSOption) error {
 	}
 
 	for i := range ls {
-		u := ls[i]
-		b.Go(func() {
-			_u, err := url.Parse(u)
-			if err != nil {
-				b.Log("[错误]", err)
-				return
-			}
-
-			filename := filepath.Join(dir, filepath.Base(_u.Path))
-			if !strings.HasSuffix(filename, ".ts") {
-				filename += ".ts"
-			}
-
-			stat, exist, err := Stat(filename)
-			if err != nil {
-				b.Log("[错误]", err)
-				b.Flush()
-				return
-			} else if exist {
-				f(u, stat.Size(), false)
-				return
-			}
-
-			var n int64
-			for x := 0; x < max(1, cfg.Retry); x++ {
-				n, err = h.GetToFile(u, filename)
-				if err == nil {
-					break
-				}
-				<-time.After(time.Second * 5)
-			}
+		seg := ls[i]
+		u := seg.URL
+		filename := filepath.Join(dir, manifest.Segments[i].Name)
+		b.GoRetryPolicy(func(ctx context.Context) error {
+			n, fresh, err := c.save(seg, filename)
 			if err != nil {
 				b.Log("[错误]", err)
-				return
+				return err
 			}
-
-			f(u, n, cfg.ShowDetails)
-		})
+			f(u, n, fresh && cfg.ShowDetails)
+			return nil
+		}, cfg.RetryPolicy)
 
 	}
 
-	b.Wait()
-
-	return nil
+	return b.Wait()
 }
 
 type DownloadHLSConfig struct {
@@ -121,6 +123,12 @@ type DownloadHLSConfig struct {
 	Coroutine   int
 	ShowDetails bool
 	Retry       int
+	RetryPolicy *RetryPolicy //重试策略,为nil时按Retry次数,间隔5秒重试
+	Variant     HLSVariant   //MasterPlaylist 选择码率,为nil时选择最高码率
+
+	Live        bool            //直播模式,定时刷新播放列表,直到 EXT-X-ENDLIST ,达到最大时长或者Context取消
+	MaxDuration time.Duration   //直播模式,最大录制时长(按分片的时长计算),0表示不限制
+	Context     context.Context //直播模式,取消时停止录制,已开始的分片继续下载完成
 }
 
 type HLSOption func(c *DownloadHLSConfig)
@@ -147,6 +155,49 @@ func WithHLSRetry(retry int) HLSOption {
 	}
 }
 
+// WithHLSRetryPolicy 设置分片下载的重试策略,例 RetryExponential(5, time.Second, time.Minute)
+func WithHLSRetryPolicy(policy *RetryPolicy) HLSOption {
+	return func(c *DownloadHLSConfig) {
+		c.RetryPolicy = policy
+	}
+}
+
+// HLSVariant 从 MasterPlaylist 的多个码率中选择一个,返回nil表示没有合适的码率
+type HLSVariant func(vs []*m3u8.Variant) *m3u8.Variant
+
+// HLSHighest 选择最高码率
+func HLSHighest() HLSVariant {
+	return m3u8.Highest
+}
+
+// HLSLowest 选择最低码率
+func HLSLowest() HLSVariant {
+	return m3u8.Lowest
+}
+
+// HLSResolution 选择分辨率最接近的码率,例 HLSResolution(1280, 720)
+func HLSResolution(width, height int) HLSVariant {
+	return HLSVariant(m3u8.Resolution(width, height))
+}
+
+// WithHLSVariant 设置 MasterPlaylist 选择码率的方式,默认选择最高码率,
+// 例 WithHLSVariant(HLSResolution(1280, 720)),也可以自定义,参数为 github.com/grafov/m3u8 的 Variant
+func WithHLSVariant(f HLSVariant) HLSOption {
+	return func(c *DownloadHLSConfig) {
+		c.Variant = f
+	}
+}
+
+// WithHLSLive 直播模式,定时刷新播放列表,录制新的分片,直到 EXT-X-ENDLIST ,达到最大时长或者ctx取消,
+// ctx为nil表示不取消,maxDuration为最大录制时长,0表示不限制
+func WithHLSLive(ctx context.Context, maxDuration time.Duration) HLSOption {
+	return func(c *DownloadHLSConfig) {
+		c.Live = true
+		c.Context = ctx
+		c.MaxDuration = maxDuration
+	}
+}
+
 // Stat 获取文件信息
 func Stat(filename string) (os.FileInfo, bool, error) {
 	stat, err := os.Stat(filename)
@@ -7,7 +7,9 @@ require (
 	github.com/grafov/m3u8 v0.12.1
 	github.com/injoyai/base v1.2.20
 	github.com/injoyai/conv v1.2.5
+	github.com/mattn/go-isatty v0.0.20
 	golang.org/x/net v0.30.0
+	golang.org/x/sys v0.26.0
 	golang.org/x/text v0.19.0
 )
 
@@ -15,13 +17,11 @@ require (
 	github.com/json-iterator/go v1.1.12 // indirect
 	github.com/kr/pretty v0.3.1 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
-	github.com/mattn/go-isatty v0.0.20 // indirect
 	github.com/modern-go/concurrent v0.0.0-20180306012644-bacd9c7ef1dd // indirect
 	github.com/modern-go/reflect2 v1.0.2 // indirect
 	github.com/pelletier/go-toml/v2 v2.1.1 // indirect
 	github.com/rogpeppe/go-internal v1.14.1 // indirect
 	github.com/stretchr/testify v1.9.0 // indirect
-	golang.org/x/sys v0.26.0 // indirect
 	gopkg.in/ini.v1 v1.67.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
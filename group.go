@@ -0,0 +1,275 @@
+package bar
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	groupFinalKeep     = iota //完成后保留在原位置
+	groupFinalPin             //完成后固定在活动区域上方
+	groupFinalCollapse        //完成后移除
+)
+
+type GroupOption func(g *Group)
+
+// WithGroupWriter 设置writer
+func WithGroupWriter(w io.Writer) GroupOption {
+	return func(g *Group) {
+		g.writer = w
+	}
+}
+
+// WithGroupPin 完成的进度条固定在活动区域上方,不再参与重绘
+func WithGroupPin() GroupOption {
+	return func(g *Group) {
+		g.final = groupFinalPin
+	}
+}
+
+// WithGroupTTY 强制使用终端模式,回到活动区域的起始位置重绘,用于不能识别的终端或者测试
+func WithGroupTTY() GroupOption {
+	return func(g *Group) {
+		g.tty = true
+	}
+}
+
+// WithGroupCollapse 完成的进度条从活动区域移除
+func WithGroupCollapse() GroupOption {
+	return func(g *Group) {
+		g.final = groupFinalCollapse
+	}
+}
+
+// NewGroup 多进度条,统一管理输出,多行同时刷新,互不覆盖
+func NewGroup(op ...GroupOption) *Group {
+	g := &Group{
+		writer: os.Stdout,
+		final:  groupFinalKeep,
+	}
+	for _, v := range op {
+		v(g)
+	}
+	g.tty = g.tty || isTerminal(g.writer)
+	return g
+}
+
+type Group struct {
+	writer io.Writer      //输出
+	final  int            //完成后的处理方式
+	tty    bool           //writer是否是终端,不是终端时只输出日志,不重绘
+	lines  []*groupLine   //活动区域的进度条,按添加顺序
+	drawn  int            //上次绘制的行数
+	mu     sync.Mutex     //并发锁
+	wg     sync.WaitGroup //等待所有进度条完成
+}
+
+// New 新建一个进度条并加入到组
+func (this *Group) New(op ...Option) *Bar {
+	l := &groupLine{group: this}
+	l.bar = New(WithWriter(l), WithOption(op...))
+	return this.add(l)
+}
+
+// Add 加入已有的进度条,会替换进度条的writer,已经结束的进度条显示最后的内容
+func (this *Group) Add(b *Bar) *Bar {
+	l := &groupLine{group: this, bar: b}
+	if b.Closed() {
+		l.text = trimControl(b.String())
+	}
+	b.SetWriter(l)
+	return this.add(l)
+}
+
+// add 加入到活动区域,进度条结束(完成事件执行完)后按完成的处理方式处理,
+// 不依赖完成事件,之后设置 OnFinal 等也不影响
+func (this *Group) add(l *groupLine) *Bar {
+	this.mu.Lock()
+	this.wg.Add(1)
+	this.lines = append(this.lines, l)
+	this.draw()
+	this.mu.Unlock()
+
+	l.bar.whenDone(func(b *Bar) {
+		this.finish(l)
+	})
+	return l.bar
+}
+
+// Remove 移除进度条,不再显示
+func (this *Group) Remove(b *Bar) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	for i, l := range this.lines {
+		if l.bar == b {
+			this.lines = append(this.lines[:i], this.lines[i+1:]...)
+			this.done(l)
+			this.draw()
+			return
+		}
+	}
+}
+
+// Len 活动区域的进度条数量
+func (this *Group) Len() int {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	return len(this.lines)
+}
+
+// Flush 重绘所有进度条
+func (this *Group) Flush() {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	this.draw()
+}
+
+// Wait 等待所有进度条完成或移除
+func (this *Group) Wait() {
+	this.wg.Wait()
+}
+
+// Logf 在活动区域上方打印日志
+func (this *Group) Logf(format string, a ...any) {
+	this.log(fmt.Sprintf(format, a...))
+}
+
+// Log 在活动区域上方打印日志
+func (this *Group) Log(a ...any) {
+	this.log(fmt.Sprintln(a...))
+}
+
+func (this *Group) log(s string) {
+	s = strings.TrimSuffix(s, "\n")
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	this.draw(s)
+}
+
+// finish 进度条完成
+func (this *Group) finish(l *groupLine) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	if l.done {
+		return
+	}
+	switch this.final {
+	case groupFinalPin:
+		this.remove(l)
+		this.done(l)
+		if this.tty {
+			this.draw(l.text)
+		}
+	case groupFinalCollapse:
+		this.remove(l)
+		this.done(l)
+		this.draw()
+	default:
+		this.done(l)
+		this.draw()
+	}
+}
+
+func (this *Group) remove(l *groupLine) {
+	for i, v := range this.lines {
+		if v == l {
+			this.lines = append(this.lines[:i], this.lines[i+1:]...)
+			return
+		}
+	}
+}
+
+func (this *Group) done(l *groupLine) {
+	if !l.done {
+		l.done = true
+		this.wg.Done()
+	}
+}
+
+// draw 回到活动区域的起始位置,打印日志(固定内容),再重绘所有进度条
+// 不是终端时只打印日志,进度条自身会以纯文本模式输出带换行的内容
+func (this *Group) draw(logs ...string) {
+	if this.writer == nil {
+		return
+	}
+	var buf strings.Builder
+	if !this.tty {
+		//不是终端,进度条以纯文本模式输出日志
+		for _, v := range logs {
+			buf.WriteString(v)
+			buf.WriteString("\n")
+		}
+		this.writer.Write([]byte(buf.String()))
+		return
+	}
+	if this.drawn > 0 {
+		buf.WriteString(fmt.Sprintf("\033[%dA", this.drawn))
+	}
+	buf.WriteString("\r\033[J")
+	for _, v := range logs {
+		buf.WriteString(v)
+		buf.WriteString("\n")
+	}
+	for _, l := range this.lines {
+		buf.WriteString(l.text)
+		buf.WriteString("\n")
+	}
+	this.drawn = len(this.lines)
+	this.writer.Write([]byte(buf.String()))
+}
+
+// groupLine 进度条在组中的一行,作为进度条的writer
+type groupLine struct {
+	group *Group
+	bar   *Bar
+	text  string //最后一次渲染的内容
+	done  bool   //是否已完成
+}
+
+func (this *groupLine) Write(p []byte) (int, error) {
+	s := trimControl(string(p))
+	g := this.group
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if strings.Contains(s, "\n") {
+		//带换行的是日志,打印在活动区域上方
+		s = strings.TrimSuffix(s, "\n")
+		if len(s) > 0 {
+			g.draw(s)
+		}
+		return len(p), nil
+	}
+	this.text = s
+	g.draw()
+	return len(p), nil
+}
+
+// isTerminal 组是否是终端模式,进度条按组的模式输出
+func (this *groupLine) isTerminal() bool {
+	return this.group.tty
+}
+
+// Fd 组的writer是终端时返回其文件描述符,用于进度条获取终端宽度
+func (this *groupLine) Fd() uintptr {
+	if f, ok := this.group.writer.(interface{ Fd() uintptr }); ok {
+		return f.Fd()
+	}
+	return ^uintptr(0)
+}
+
+// trimControl 去除开头的回车和清行控制符
+func trimControl(s string) string {
+	for {
+		switch {
+		case strings.HasPrefix(s, "\r"):
+			s = s[1:]
+		case strings.HasPrefix(s, "\033[K"):
+			s = s[3:]
+		default:
+			return s
+		}
+	}
+}
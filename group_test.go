@@ -0,0 +1,174 @@
+package bar_test
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/injoyai/bar"
+	"github.com/injoyai/bar/bartest"
+)
+
+// screen 模拟终端,按组输出的控制符(回到上n行,清屏到末尾,清行)计算屏幕上最后显示的内容
+func screen(s string) []string {
+	lines := []string{""}
+	row := 0
+	for len(s) > 0 {
+		switch {
+		case strings.HasPrefix(s, "\033["):
+			i := strings.IndexAny(s[2:], "ABCDJKm") + 2
+			switch s[i] {
+			case 'A':
+				n, _ := strconv.Atoi(s[2:i])
+				row = max(row-n, 0)
+			case 'J':
+				lines = lines[:row+1]
+				lines[row] = ""
+			case 'K':
+				lines[row] = ""
+			}
+			s = s[i+1:]
+		case s[0] == '\r':
+			s = s[1:]
+		case s[0] == '\n':
+			row++
+			if row == len(lines) {
+				lines = append(lines, "")
+			}
+			s = s[1:]
+		default:
+			i := strings.IndexAny(s, "\033\r\n")
+			if i < 0 {
+				i = len(s)
+			}
+			lines[row] += s[:i]
+			s = s[i:]
+		}
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func newGroup(op ...bar.GroupOption) (*bar.Group, *bartest.Recorder, func(prefix string) *bar.Bar) {
+	r := bartest.NewRecorder()
+	g := bar.NewGroup(append([]bar.GroupOption{bar.WithGroupWriter(r), bar.WithGroupTTY()}, op...)...)
+	return g, r, func(prefix string) *bar.Bar {
+		return g.New(
+			bar.WithTotal(10),
+			bar.WithFormat(bar.WithRateSize()),
+			bar.WithPrefix(prefix),
+			bar.WithRefreshRate(0),
+		)
+	}
+}
+
+func checkScreen(t *testing.T, r *bartest.Recorder, want ...string) {
+	t.Helper()
+	got := screen(r.String())
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("screen: got %q, want %q", got, want)
+	}
+}
+
+// waitGroup 等待组内的进度条都结束,超时则失败
+func waitGroup(t *testing.T, g *bar.Group) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		g.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("group wait timeout")
+	}
+}
+
+func TestGroupRedraw(t *testing.T) {
+	g, r, newBar := newGroup()
+	a, b := newBar("a "), newBar("b ")
+	a.Add(5).Flush()
+	b.Add(3).Flush()
+	checkScreen(t, r, "a 5/10", "b 3/10")
+
+	g.Log("日志")
+	a.Log("进度条的日志")
+	a.Add(1).Flush()
+	checkScreen(t, r, "日志", "进度条的日志", "a 6/10", "b 3/10")
+
+	a.Finish()
+	b.Fail(nil)
+	waitGroup(t, g)
+	checkScreen(t, r, "日志", "进度条的日志", "a 10/10  ✓", "b 3/10  ✗")
+}
+
+func TestGroupPin(t *testing.T) {
+	g, r, newBar := newGroup(bar.WithGroupPin())
+	a, b := newBar("a "), newBar("b ")
+	a.Add(5).Flush()
+	b.Add(3).Flush()
+	b.Finish()
+	if g.Len() != 1 {
+		t.Fatalf("len: got %d", g.Len())
+	}
+	checkScreen(t, r, "b 10/10  ✓", "a 5/10")
+
+	//固定的内容不再重绘
+	a.Add(1).Flush()
+	g.Log("日志")
+	checkScreen(t, r, "b 10/10  ✓", "日志", "a 6/10")
+	a.Finish()
+	waitGroup(t, g)
+	checkScreen(t, r, "b 10/10  ✓", "日志", "a 10/10  ✓")
+}
+
+func TestGroupCollapse(t *testing.T) {
+	g, r, newBar := newGroup(bar.WithGroupCollapse())
+	a, b := newBar("a "), newBar("b ")
+	a.Add(5).Flush()
+	b.Add(3).Flush()
+	a.Finish()
+	checkScreen(t, r, "b 3/10")
+	b.Finish()
+	waitGroup(t, g)
+	checkScreen(t, r)
+	if g.Len() != 0 {
+		t.Fatalf("len: got %d", g.Len())
+	}
+}
+
+func TestGroupRemove(t *testing.T) {
+	g, r, newBar := newGroup()
+	a, b := newBar("a "), newBar("b ")
+	a.Add(5).Flush()
+	b.Add(3).Flush()
+	g.Remove(b)
+	checkScreen(t, r, "a 5/10")
+	a.Finish()
+	waitGroup(t, g)
+	checkScreen(t, r, "a 10/10  ✓")
+}
+
+// TestGroupFinal 加入组之后设置完成事件,或者加入已经结束的进度条,都不影响等待
+func TestGroupFinal(t *testing.T) {
+	g, r, newBar := newGroup()
+	called := 0
+	a := newBar("a ")
+	a.OnFinal(func(b *bar.Bar) { called++ })
+	bar.WithFinalResult(func(b *bar.Bar, state bar.State, err error) { called++ })(a)
+
+	b := bar.New(bar.WithTotal(10), bar.WithFormat(bar.WithRateSize()), bar.WithPrefix("b "), bar.WithWriter(bartest.NewRecorder()), bar.WithTTY())
+	b.Finish()
+	g.Add(b)
+
+	a.Finish()
+	waitGroup(t, g)
+	if called != 2 {
+		t.Fatalf("final: called %d", called)
+	}
+	checkScreen(t, r, "a 10/10  ✓", "b 10/10  ✓")
+}
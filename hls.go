@@ -0,0 +1,406 @@
+package bar
+
+import (
+	"cmp"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/injoyai/bar/internal/m3u8"
+	"github.com/injoyai/bar/internal/util"
+)
+
+// hlsClient 下载分片,缓存分片的密钥,每个密钥地址只下载一次
+type hlsClient struct {
+	client *util.Client
+	cache  map[string][]byte
+	mu     sync.Mutex
+}
+
+// get 获取密钥,缓存中没有时下载,下载失败不缓存,由重试再次下载
+func (this *hlsClient) get(uri string) ([]byte, error) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	if key, ok := this.cache[uri]; ok {
+		return key, nil
+	}
+	resp, err := this.client.Get(uri)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("密钥下载失败, status=%d", resp.StatusCode)
+	}
+	key, err := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != 16 {
+		return nil, fmt.Errorf("AES-128 密钥长度必须是16字节, 得到%d字节", len(key))
+	}
+	if this.cache == nil {
+		this.cache = map[string][]byte{}
+	}
+	this.cache[uri] = key
+	return key, nil
+}
+
+// save 下载分片到filename,文件已存在时跳过,返回文件的大小和是否是新下载的
+func (this *hlsClient) save(seg *m3u8.Segment, filename string) (int64, bool, error) {
+	stat, exist, err := Stat(filename)
+	if err != nil {
+		return 0, false, err
+	} else if exist {
+		return stat.Size(), false, nil
+	}
+	n, err := this.download(seg, filename)
+	return n, err == nil, err
+}
+
+// download 下载分片,加密的分片边下载边解密,先写入临时文件,完成后再重命名,返回写入的字节数
+func (this *hlsClient) download(seg *m3u8.Segment, filename string) (int64, error) {
+	if seg.Key == nil {
+		return this.client.GetToFile(seg.URL, filename)
+	}
+	if seg.Key.Method != "AES-128" {
+		return 0, errors.New("不支持的加密方式: " + seg.Key.Method)
+	}
+
+	key, err := this.get(seg.Key.URI)
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := this.client.Get(seg.URL)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("分片下载失败, status=%d", resp.StatusCode)
+	}
+
+	r, err := m3u8.NewDecryptReader(resp.Body, key, seg.Key.IVFor(seg.Sequence))
+	if err != nil {
+		return 0, err
+	}
+
+	tmp := filename + ".downloading"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(f, r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return 0, err
+	}
+	return n, os.Rename(tmp, filename)
+}
+
+// MergeHLS 按顺序合并dir下的ts分片到output,TS格式可以直接拼接,不依赖ffmpeg,按写入的字节显示进度
+func MergeHLS(dir, output string, op ...MergeOption) error {
+	cfg := &MergeHLSConfig{}
+	for _, v := range op {
+		v(cfg)
+	}
+
+	names, err := hlsSegments(dir, output)
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return errors.New("没有找到ts分片: " + dir)
+	}
+
+	if err = os.MkdirAll(filepath.Dir(output), os.ModePerm); err != nil {
+		return err
+	}
+
+	if cfg.FFmpeg {
+		err = m3u8.MergeByFFmpeg(dir, names, output)
+	} else {
+		err = mergeTS(dir, names, output, cfg.Options...)
+	}
+	if err != nil || !cfg.Delete {
+		return err
+	}
+
+	for _, name := range append(names, HLSManifestName) {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeTS 直接拼接ts分片,先写入临时文件,完成后再重命名
+func mergeTS(dir string, names []string, output string, op ...Option) error {
+	total := int64(0)
+	for _, name := range names {
+		info, err := os.Stat(filepath.Join(dir, name))
+		if err != nil {
+			return err
+		}
+		total += info.Size()
+	}
+
+	b := New(
+		WithTotal(total),
+		WithFormatDefaultUnit(),
+		WithOption(op...),
+		WithFlush(),
+	)
+
+	tmp := output + ".merging"
+	f, err := os.Create(tmp)
+	if err != nil {
+		b.Fail(err)
+		return err
+	}
+
+	err = func() error {
+		for _, name := range names {
+			r, err := os.Open(filepath.Join(dir, name))
+			if err != nil {
+				return err
+			}
+			_, err = b.Copy(f, r)
+			r.Close()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}()
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, output)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		b.Fail(err)
+		return err
+	}
+	b.Finish()
+	return nil
+}
+
+// hlsSegments dir下的ts分片的文件名,存在清单文件时按清单的顺序(播放列表的顺序),
+// 否则按文件名的自然顺序(seg2.ts 在 seg10.ts 之前),不包括output
+func hlsSegments(dir, output string) ([]string, error) {
+	m, err := LoadHLSManifest(filepath.Join(dir, HLSManifestName))
+	if err == nil {
+		names := make([]string, 0, len(m.Segments))
+		for _, v := range m.Segments {
+			if _, err := os.Stat(filepath.Join(dir, v.Name)); err != nil {
+				return nil, fmt.Errorf("分片[%d]不存在: %w", v.Sequence, err)
+			}
+			names = append(names, v.Name)
+		}
+		return names, nil
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	es, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	out, _ := filepath.Abs(output)
+	var names []string
+	for _, e := range es {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".ts") {
+			continue
+		}
+		if p, _ := filepath.Abs(filepath.Join(dir, e.Name())); p == out {
+			continue
+		}
+		names = append(names, e.Name())
+	}
+	slices.SortFunc(names, naturalCompare)
+	return names, nil
+}
+
+// naturalCompare 自然顺序比较,数字部分按数值比较,例 seg2 < seg10
+func naturalCompare(a, b string) int {
+	for a != "" && b != "" {
+		da, db := digits(a), digits(b)
+		if da > 0 && db > 0 {
+			na, nb := strings.TrimLeft(a[:da], "0"), strings.TrimLeft(b[:db], "0")
+			if c := cmp.Compare(len(na), len(nb)); c != 0 {
+				return c
+			}
+			if c := strings.Compare(na, nb); c != 0 {
+				return c
+			}
+			a, b = a[da:], b[db:]
+			continue
+		}
+		if a[0] != b[0] {
+			return cmp.Compare(a[0], b[0])
+		}
+		a, b = a[1:], b[1:]
+	}
+	return cmp.Compare(len(a), len(b))
+}
+
+// digits 开头连续数字的长度
+func digits(s string) int {
+	n := 0
+	for n < len(s) && s[n] >= '0' && s[n] <= '9' {
+		n++
+	}
+	return n
+}
+
+// HLSManifestName 分片清单的文件名,由 DownloadHLS 写入分片目录
+const HLSManifestName = "manifest.json"
+
+// HLSManifest 分片清单,记录播放列表的顺序和分片在本地的文件名,合并时按清单的顺序
+type HLSManifest struct {
+	Source   string               `json:"source"`   //m3u8地址
+	Segments []HLSManifestSegment `json:"segments"` //按播放列表顺序的分片
+}
+
+// HLSManifestSegment 清单中的分片
+type HLSManifestSegment struct {
+	URL      string          `json:"url"`           //分片地址
+	Name     string          `json:"name"`          //本地的文件名,相对分片目录,不会重复
+	Sequence uint64          `json:"sequence"`      //媒体序号
+	Duration float64         `json:"duration"`      //时长(秒)
+	Key      *HLSManifestKey `json:"key,omitempty"` //加密的密钥,用于重新下载
+}
+
+// HLSManifestKey 清单中分片加密的密钥
+type HLSManifestKey struct {
+	Method string `json:"method"`       //加密方式
+	URI    string `json:"uri"`          //密钥的地址
+	IV     string `json:"iv,omitempty"` //初始向量(十六进制),为空表示根据媒体序号生成
+}
+
+// newHLSManifestSegment 清单中的分片,index为播放列表中的序号
+func newHLSManifestSegment(index, width int, seg *m3u8.Segment) HLSManifestSegment {
+	v := HLSManifestSegment{
+		URL:      seg.URL,
+		Name:     hlsName(index, width, seg.URL),
+		Sequence: seg.Sequence,
+		Duration: seg.Duration,
+	}
+	if seg.Key != nil {
+		v.Key = &HLSManifestKey{
+			Method: seg.Key.Method,
+			URI:    seg.Key.URI,
+			IV:     hex.EncodeToString(seg.Key.IV),
+		}
+	}
+	return v
+}
+
+// segment 转换成下载的分片
+func (this HLSManifestSegment) segment() (*m3u8.Segment, error) {
+	seg := &m3u8.Segment{
+		URL:      this.URL,
+		Sequence: this.Sequence,
+		Duration: this.Duration,
+	}
+	if this.Key != nil {
+		seg.Key = &m3u8.Key{Method: this.Key.Method, URI: this.Key.URI}
+		if this.Key.IV != "" {
+			iv, err := hex.DecodeString(this.Key.IV)
+			if err != nil {
+				return nil, err
+			}
+			seg.Key.IV = iv
+		}
+	}
+	return seg, nil
+}
+
+// LoadHLSManifest 读取分片清单
+func LoadHLSManifest(filename string) (*HLSManifest, error) {
+	bs, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	m := new(HLSManifest)
+	err = json.Unmarshal(bs, m)
+	return m, err
+}
+
+// Save 保存分片清单
+func (this *HLSManifest) Save(filename string) error {
+	return saveJSON(filename, this)
+}
+
+// newHLSManifest 生成分片清单,文件名为播放列表的序号加上地址的文件名,例 00012_index.ts
+func newHLSManifest(source string, ls []*m3u8.Segment) *HLSManifest {
+	m := &HLSManifest{
+		Source:   source,
+		Segments: make([]HLSManifestSegment, len(ls)),
+	}
+	width := max(len(strconv.Itoa(len(ls))), 5)
+	for i, seg := range ls {
+		m.Segments[i] = newHLSManifestSegment(i, width, seg)
+	}
+	return m
+}
+
+// hlsName 分片的本地文件名,序号保证不重复,并且按文件名排序时和播放列表顺序一致
+func hlsName(index, width int, u string) string {
+	name := fmt.Sprintf("%0*d", width, index)
+	if _u, err := url.Parse(u); err == nil {
+		base := strings.TrimSuffix(path.Base(_u.Path), ".ts")
+		if base != "" && base != "." && base != "/" {
+			name += "_" + base
+		}
+	}
+	return name + ".ts"
+}
+
+// MergeHLSConfig 合并分片的配置
+type MergeHLSConfig struct {
+	Delete  bool     //合并成功后删除分片文件
+	FFmpeg  bool     //使用ffmpeg合并(重新封装),需要安装ffmpeg,不显示进度
+	Options []Option //进度条的选项
+}
+
+type MergeOption func(c *MergeHLSConfig)
+
+// WithMergeDelete 合并成功后删除分片文件
+func WithMergeDelete(b ...bool) MergeOption {
+	return func(c *MergeHLSConfig) {
+		c.Delete = len(b) == 0 || b[0]
+	}
+}
+
+// WithMergeFFmpeg 使用ffmpeg合并,需要安装ffmpeg
+func WithMergeFFmpeg(b ...bool) MergeOption {
+	return func(c *MergeHLSConfig) {
+		c.FFmpeg = len(b) == 0 || b[0]
+	}
+}
+
+// WithMergeBar 设置进度条的选项
+func WithMergeBar(op ...Option) MergeOption {
+	return func(c *MergeHLSConfig) {
+		c.Options = append(c.Options, op...)
+	}
+}
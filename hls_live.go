@@ -0,0 +1,220 @@
+package bar
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/injoyai/bar/internal/m3u8"
+	"github.com/injoyai/bar/internal/util"
+)
+
+// downloadHLSLive 录制直播,按目标时长定时刷新播放列表,按媒体序号去重,新的分片加入下载队列,
+// 遇到 EXT-X-ENDLIST ,达到最大时长或者ctx取消时停止刷新,等待已加入的分片下载完成
+func downloadHLSLive(source, dir string, cfg *DownloadHLSConfig) error {
+	ctx := cfg.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	media, base, err := m3u8.Media(source, m3u8.Select(cfg.Variant))
+	if err != nil {
+		return err
+	}
+
+	h := util.NewClient().SetTimeout(0).SetKeepAlive()
+	if err := h.SetProxy(cfg.Proxy); err != nil {
+		return err
+	}
+	c := &hlsClient{client: h}
+
+	var (
+		size     int64 //已下载的字节
+		recorded int64 //已录制的时长
+	)
+	b := NewCoroutine(0, cfg.Coroutine)
+	b.SetFormat(
+		WithPlan(),
+		WithRateSize(),
+		WithCustomSize(&size),
+		WithCustomDuration(&recorded),
+		WithCoroutineRetry(b),
+		WithUsed(),
+	)
+
+	//新的分片加入无界的队列,刷新播放列表不会因为协程数量已满而阻塞
+	q := newHLSQueue(func(f func(ctx context.Context) error) {
+		b.GoRetryPolicy(f, cfg.RetryPolicy)
+	})
+	enqueue := func(seg *m3u8.Segment, name string) {
+		filename := filepath.Join(dir, name)
+		q.push(func(ctx context.Context) error {
+			n, fresh, err := c.save(seg, filename)
+			if err != nil {
+				b.Log("[错误]", err)
+				return err
+			}
+			atomic.AddInt64(&size, n)
+			atomic.AddInt64(&recorded, int64(seg.Duration*float64(time.Second)))
+			if fresh && cfg.ShowDetails {
+				b.Log(seg.URL)
+			}
+			b.Flush()
+			return nil
+		})
+	}
+
+	manifest := &HLSManifest{Source: source}
+	manifestName := filepath.Join(dir, HLSManifestName)
+	seen := map[uint64]bool{}
+	scheduled := time.Duration(0)
+
+	//继续之前的录制,已记录的分片不重复加入,文件名不重复,
+	//文件不存在的分片(例如下载前进程退出)重新加入下载队列
+	if m, err := LoadHLSManifest(manifestName); err == nil && m.Source == source {
+		manifest = m
+		for _, v := range m.Segments {
+			seen[v.Sequence] = true
+			scheduled += time.Duration(v.Duration * float64(time.Second))
+			seg, err := v.segment()
+			if err != nil {
+				b.Log("[错误]", err)
+				continue
+			}
+			enqueue(seg, v.Name)
+		}
+	}
+
+	for {
+		//新的分片先记录到清单,每次刷新保存一次清单,再加入下载队列
+		ls, err := m3u8.Segments(media, base)
+		if err != nil {
+			b.Log("[错误]", err)
+		}
+		var added []*m3u8.Segment
+		for _, seg := range ls {
+			if seen[seg.Sequence] {
+				continue
+			}
+			if cfg.MaxDuration > 0 && scheduled >= cfg.MaxDuration {
+				break
+			}
+			seen[seg.Sequence] = true
+			scheduled += time.Duration(seg.Duration * float64(time.Second))
+			manifest.Segments = append(manifest.Segments, newHLSManifestSegment(len(manifest.Segments), 5, seg))
+			added = append(added, seg)
+		}
+		if len(added) > 0 {
+			if err := manifest.Save(manifestName); err != nil {
+				b.Log("[错误]", err)
+			}
+			offset := len(manifest.Segments) - len(added)
+			for i, seg := range added {
+				enqueue(seg, manifest.Segments[offset+i].Name)
+			}
+		}
+
+		if media.Closed || (cfg.MaxDuration > 0 && scheduled >= cfg.MaxDuration) {
+			break
+		}
+
+		//按目标时长刷新,没有新的分片时间隔减半
+		interval := time.Duration(media.TargetDuration * float64(time.Second))
+		if len(added) == 0 {
+			interval /= 2
+		}
+		interval = max(interval, time.Second)
+		t := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+		case <-t.C:
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		//刷新失败时下次再试,不中断录制
+		if m, u, err := m3u8.Media(base.String()); err != nil {
+			b.Log("[错误]", err)
+		} else {
+			media, base = m, u
+		}
+	}
+
+	//等待队列中的分片都提交后,再等待下载完成
+	q.close()
+	err = b.Wait()
+	if err != nil {
+		b.Fail(err)
+	} else {
+		b.Finish()
+	}
+	return err
+}
+
+// hlsQueue 无界的下载队列,加入时不阻塞,由单独的协程按顺序提交任务,
+// 提交时可能因为协程数量已满而阻塞,但不会影响加入
+type hlsQueue struct {
+	mu     sync.Mutex
+	items  []func(ctx context.Context) error
+	closed bool
+	notify chan struct{}
+	done   chan struct{}
+}
+
+func newHLSQueue(submit func(f func(ctx context.Context) error)) *hlsQueue {
+	q := &hlsQueue{
+		notify: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+	go q.run(submit)
+	return q
+}
+
+// push 加入任务,不阻塞
+func (this *hlsQueue) push(f func(ctx context.Context) error) {
+	this.mu.Lock()
+	this.items = append(this.items, f)
+	this.mu.Unlock()
+	this.wake()
+}
+
+// close 不再加入任务,等待已加入的任务都提交
+func (this *hlsQueue) close() {
+	this.mu.Lock()
+	this.closed = true
+	this.mu.Unlock()
+	this.wake()
+	<-this.done
+}
+
+func (this *hlsQueue) wake() {
+	select {
+	case this.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (this *hlsQueue) run(submit func(f func(ctx context.Context) error)) {
+	defer close(this.done)
+	for {
+		this.mu.Lock()
+		if len(this.items) > 0 {
+			f := this.items[0]
+			this.items[0] = nil
+			this.items = this.items[1:]
+			this.mu.Unlock()
+			submit(f)
+			continue
+		}
+		closed := this.closed
+		this.mu.Unlock()
+		if closed {
+			return
+		}
+		<-this.notify
+	}
+}
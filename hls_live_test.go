@@ -0,0 +1,102 @@
+package bar
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestHLSLiveResume 继续录制时,清单中文件不存在的分片重新下载,已存在的不重复下载
+func TestHLSLiveResume(t *testing.T) {
+	var mu sync.Mutex
+	requests := map[string]int{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/live.m3u8":
+			//分片0和1已经不在直播的窗口中
+			fmt.Fprint(w, "#EXTM3U\n#EXT-X-TARGETDURATION:1\n#EXT-X-MEDIA-SEQUENCE:2\n#EXTINF:1,\nseg2.ts\n#EXT-X-ENDLIST\n")
+		default:
+			fmt.Fprint(w, "data"+r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+
+	source := srv.URL + "/live.m3u8"
+	dir := t.TempDir()
+	m := &HLSManifest{Source: source, Segments: []HLSManifestSegment{
+		{URL: srv.URL + "/seg0.ts", Name: "00000_seg0.ts", Sequence: 0, Duration: 1},
+		{URL: srv.URL + "/seg1.ts", Name: "00001_seg1.ts", Sequence: 1, Duration: 1},
+	}}
+	if err := m.Save(filepath.Join(dir, HLSManifestName)); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "00000_seg0.ts"), []byte("data/seg0.ts"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	err := DownloadHLS(source, dir, WithHLSLive(context.Background(), 0), WithHLSRetryPolicy(RetryFixed(1, 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"00000_seg0.ts", "00001_seg1.ts", "00002_seg2.ts"} {
+		bs, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "data/" + name[6:]; string(bs) != want {
+			t.Fatalf("%s: %q != %q", name, bs, want)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if requests["/seg0.ts"] != 0 || requests["/seg1.ts"] != 1 || requests["/seg2.ts"] != 1 {
+		t.Fatalf("requests: %v", requests)
+	}
+
+	m, err = LoadHLSManifest(filepath.Join(dir, HLSManifestName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Segments) != 3 || m.Segments[2].Sequence != 2 {
+		t.Fatalf("manifest: %+v", m.Segments)
+	}
+}
+
+// TestHLSQueue 提交阻塞时加入任务不阻塞,关闭时等待全部提交
+func TestHLSQueue(t *testing.T) {
+	release := make(chan struct{})
+	submitted := 0
+	q := newHLSQueue(func(f func(ctx context.Context) error) {
+		<-release
+		submitted++
+	})
+
+	pushed := make(chan struct{})
+	go func() {
+		for i := 0; i < 100; i++ {
+			q.push(func(ctx context.Context) error { return nil })
+		}
+		close(pushed)
+	}()
+	select {
+	case <-pushed:
+	case <-time.After(time.Second * 5):
+		t.Fatal("push blocked")
+	}
+
+	close(release)
+	q.close()
+	if submitted != 100 {
+		t.Fatalf("submitted %d != 100", submitted)
+	}
+}
@@ -0,0 +1,122 @@
+package bar
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestNaturalCompare(t *testing.T) {
+	for _, v := range []struct {
+		a, b string
+		want int
+	}{
+		{"seg2.ts", "seg10.ts", -1},
+		{"seg10.ts", "seg2.ts", 1},
+		{"seg2.ts", "seg2.ts", 0},
+		{"seg02.ts", "seg2.ts", 0},
+		{"seg002.ts", "seg10.ts", -1},
+		{"a.ts", "b.ts", -1},
+		{"seg1", "seg1.ts", -1},
+		{"seg9a", "seg9b", -1},
+		{"1_10.ts", "1_9.ts", 1},
+		{"10.ts", "9.ts", 1},
+		{"seg.ts", "seg1.ts", -1},
+		{"", "a", -1},
+		{"99999999999999999999.ts", "100000000000000000000.ts", -1},
+	} {
+		if got := naturalCompare(v.a, v.b); got != v.want {
+			t.Errorf("naturalCompare(%q, %q): got %d, want %d", v.a, v.b, got, v.want)
+		}
+	}
+
+	ls := []string{"seg10.ts", "seg1.ts", "seg2.ts", "seg100.ts", "seg20.ts"}
+	slices.SortFunc(ls, naturalCompare)
+	if want := []string{"seg1.ts", "seg2.ts", "seg10.ts", "seg20.ts", "seg100.ts"}; !slices.Equal(ls, want) {
+		t.Fatalf("sort: got %q, want %q", ls, want)
+	}
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func readFile(t *testing.T, filename string) string {
+	t.Helper()
+	bs, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(bs)
+}
+
+// TestMergeHLS 存在清单时按清单的顺序合并,只合并清单中的分片,否则按文件名的自然顺序
+func TestMergeHLS(t *testing.T) {
+	quiet := WithMergeBar(WithWriter(io.Discard))
+
+	//按清单的顺序
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"00000_seg10.ts": "a",
+		"00001_seg2.ts":  "b",
+		"00002_seg1.ts":  "c",
+		"other.ts":       "x",
+	})
+	m := &HLSManifest{Source: "http://example.com/index.m3u8", Segments: []HLSManifestSegment{
+		{Name: "00000_seg10.ts", Sequence: 10},
+		{Name: "00001_seg2.ts", Sequence: 11},
+		{Name: "00002_seg1.ts", Sequence: 12},
+	}}
+	if err := m.Save(filepath.Join(dir, HLSManifestName)); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(dir, "out.ts")
+	if err := MergeHLS(dir, output, quiet, WithMergeDelete()); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, output); got != "abc" {
+		t.Fatalf("manifest order: got %q", got)
+	}
+	for _, name := range []string{"00000_seg10.ts", "00001_seg2.ts", "00002_seg1.ts", HLSManifestName} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Fatalf("%s not deleted: %v", name, err)
+		}
+	}
+	if got := readFile(t, filepath.Join(dir, "other.ts")); got != "x" {
+		t.Fatalf("other.ts: got %q", got)
+	}
+
+	//没有清单,按自然顺序,不包括输出文件
+	dir = t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"seg10.ts": "c",
+		"seg2.ts":  "b",
+		"seg1.ts":  "a",
+		"out.ts":   "old",
+	})
+	output = filepath.Join(dir, "out.ts")
+	if err := MergeHLS(dir, output, quiet); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, output); got != "abc" {
+		t.Fatalf("natural order: got %q", got)
+	}
+
+	//清单中的分片不存在
+	dir = t.TempDir()
+	writeFiles(t, dir, map[string]string{"00000_seg1.ts": "a"})
+	m.Segments = []HLSManifestSegment{{Name: "00000_seg1.ts", Sequence: 1}, {Name: "00001_seg2.ts", Sequence: 2}}
+	if err := m.Save(filepath.Join(dir, HLSManifestName)); err != nil {
+		t.Fatal(err)
+	}
+	if err := MergeHLS(dir, filepath.Join(dir, "out.ts"), quiet); err == nil {
+		t.Fatal("missing segment: no error")
+	}
+}
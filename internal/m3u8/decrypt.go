@@ -0,0 +1,108 @@
+package m3u8
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// IVFor 分片的初始向量,没有指定时为媒体序号的16字节大端表示
+func (this *Key) IVFor(sequence uint64) []byte {
+	if this.IV != nil {
+		return this.IV
+	}
+	iv := make([]byte, aes.BlockSize)
+	binary.BigEndian.PutUint64(iv[8:], sequence)
+	return iv
+}
+
+// NewDecryptReader AES-128 CBC 边读边解密,最后去掉 PKCS7 填充
+func NewDecryptReader(r io.Reader, key, iv []byte) (io.Reader, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != aes.BlockSize {
+		return nil, errors.New("IV 长度必须是16字节")
+	}
+	return &decryptReader{
+		r:    r,
+		mode: cipher.NewCBCDecrypter(block, iv),
+	}, nil
+}
+
+// decryptReader 解密的reader,保留最后一个块,读取结束时才能确定填充的长度
+type decryptReader struct {
+	r    io.Reader
+	mode cipher.BlockMode
+	in   []byte //未解密的数据
+	out  []byte //已解密未读取的数据
+	buf  []byte //读取的缓存
+	eof  bool
+}
+
+func (this *decryptReader) Read(p []byte) (int, error) {
+	for len(this.out) == 0 {
+		if this.eof {
+			return 0, io.EOF
+		}
+		if err := this.fill(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, this.out)
+	this.out = this.out[n:]
+	return n, nil
+}
+
+// fill 读取并解密数据,保留最后一个完整的块,直到读取结束
+func (this *decryptReader) fill() error {
+	if this.buf == nil {
+		this.buf = make([]byte, 32<<10)
+	}
+	n, err := this.r.Read(this.buf)
+	this.in = append(this.in, this.buf[:n]...)
+	switch {
+	case err == io.EOF:
+		this.eof = true
+		if len(this.in)%aes.BlockSize != 0 {
+			return errors.New("密文长度不是16的倍数")
+		}
+		if len(this.in) == 0 {
+			return nil
+		}
+		this.mode.CryptBlocks(this.in, this.in)
+		out, err := unpad(this.in)
+		if err != nil {
+			return err
+		}
+		this.out, this.in = out, nil
+		return nil
+	case err != nil:
+		return err
+	}
+
+	//保留最后一个块(可能包含填充)
+	size := (len(this.in)/aes.BlockSize - 1) * aes.BlockSize
+	if size <= 0 {
+		return nil
+	}
+	out := make([]byte, size)
+	this.mode.CryptBlocks(out, this.in[:size])
+	this.in = append(this.in[:0], this.in[size:]...)
+	this.out = out
+	return nil
+}
+
+// unpad 去掉 PKCS7 填充
+func unpad(bs []byte) ([]byte, error) {
+	n := int(bs[len(bs)-1])
+	if n == 0 || n > aes.BlockSize || n > len(bs) ||
+		!bytes.Equal(bs[len(bs)-n:], bytes.Repeat([]byte{byte(n)}, n)) {
+		return nil, errors.New("无效的 PKCS7 填充,可能是密钥错误")
+	}
+	return bs[:len(bs)-n], nil
+}
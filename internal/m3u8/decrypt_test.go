@@ -0,0 +1,134 @@
+package m3u8
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"io"
+	"net/url"
+	"testing"
+	"testing/iotest"
+
+	"github.com/grafov/m3u8"
+)
+
+var testKey = []byte("0123456789abcdef")
+
+// encrypt AES-128 CBC 加密,PKCS7 填充
+func encrypt(t *testing.T, data, key, iv []byte) []byte {
+	t.Helper()
+	n := aes.BlockSize - len(data)%aes.BlockSize
+	data = append(append([]byte(nil), data...), bytes.Repeat([]byte{byte(n)}, n)...)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := make([]byte, len(data))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(out, data)
+	return out
+}
+
+func decrypt(t *testing.T, r io.Reader, iv []byte) ([]byte, error) {
+	t.Helper()
+	d, err := NewDecryptReader(r, testKey, iv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return io.ReadAll(d)
+}
+
+func TestDecryptRoundTrip(t *testing.T) {
+	iv := bytes.Repeat([]byte{7}, aes.BlockSize)
+	readers := map[string]func(io.Reader) io.Reader{
+		"plain":    func(r io.Reader) io.Reader { return r },
+		"oneByte":  iotest.OneByteReader,
+		"half":     iotest.HalfReader,
+		"dataErr":  iotest.DataErrReader,
+		"dataErr1": func(r io.Reader) io.Reader { return iotest.DataErrReader(iotest.OneByteReader(r)) },
+	}
+	//覆盖空数据,不足一个块,正好是块的整数倍(整块填充),以及跨越读取缓存的长度
+	for _, size := range []int{0, 1, 15, 16, 17, 32, 1000, 32<<10 - 1, 32 << 10, 32<<10 + 1, 100 << 10} {
+		data := make([]byte, size)
+		for i := range data {
+			data[i] = byte(i * 31)
+		}
+		enc := encrypt(t, data, testKey, iv)
+		for name, wrap := range readers {
+			got, err := decrypt(t, wrap(bytes.NewReader(enc)), iv)
+			if err != nil {
+				t.Fatalf("size %d %s: %v", size, name, err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Fatalf("size %d %s: content mismatch", size, name)
+			}
+		}
+	}
+}
+
+func TestDecryptBadPadding(t *testing.T) {
+	iv := make([]byte, aes.BlockSize)
+	enc := encrypt(t, []byte("hello world"), testKey, iv)
+
+	//错误的密钥导致填充无效
+	d, err := NewDecryptReader(bytes.NewReader(enc), []byte("fedcba9876543210"), iv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadAll(d); err == nil {
+		t.Fatal("expected padding error with wrong key")
+	}
+
+	//长度不是块的整数倍
+	if _, err := decrypt(t, bytes.NewReader(enc[:len(enc)-1]), iv); err == nil {
+		t.Fatal("expected error for truncated ciphertext")
+	}
+
+	//填充的字节不一致
+	block, _ := aes.NewCipher(testKey)
+	plain := append(bytes.Repeat([]byte{'a'}, 13), 1, 2, 3)
+	bad := make([]byte, len(plain))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(bad, plain)
+	if _, err := decrypt(t, bytes.NewReader(bad), iv); err == nil {
+		t.Fatal("expected error for inconsistent padding")
+	}
+}
+
+func TestDecryptIV(t *testing.T) {
+	base, _ := url.Parse("https://example.com/live/index.m3u8")
+	data := []byte("segment data")
+
+	//明确指定的IV
+	k, err := newKey(&m3u8.Key{Method: "AES-128", URI: "key.bin", IV: "0x000102030405060708090A0B0C0D0E0F"}, base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k.URI != "https://example.com/live/key.bin" {
+		t.Fatalf("key uri: %s", k.URI)
+	}
+	explicit := []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+	got, err := decrypt(t, bytes.NewReader(encrypt(t, data, testKey, explicit)), k.IVFor(99))
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("explicit iv: %q %v", got, err)
+	}
+
+	//根据媒体序号生成的IV
+	k, err = newKey(&m3u8.Key{Method: "AES-128", URI: "key.bin"}, base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seq := make([]byte, aes.BlockSize)
+	binary.BigEndian.PutUint64(seq[8:], 1234)
+	if !bytes.Equal(k.IVFor(1234), seq) {
+		t.Fatalf("derived iv: %x", k.IVFor(1234))
+	}
+	got, err = decrypt(t, bytes.NewReader(encrypt(t, data, testKey, seq)), k.IVFor(1234))
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("derived iv: %q %v", got, err)
+	}
+
+	//无效的IV
+	if _, err := newKey(&m3u8.Key{Method: "AES-128", URI: "key.bin", IV: "0x0102"}, base); err == nil {
+		t.Fatal("expected error for short iv")
+	}
+}
@@ -4,68 +4,169 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	neturl "net/url"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/grafov/m3u8"
-	"github.com/injoyai/bar/internal/shell"
 )
 
 // defaultClient 用于下载 m3u8 索引文件，设置合理超时避免无限阻塞
 var defaultClient = &http.Client{Timeout: 30 * time.Second}
 
-func Decode(url string) ([]string, error) {
+// Variant 码率,同 m3u8.Variant
+type Variant = m3u8.Variant
 
-	// 校验 URL 必须含 "/"，否则后续 baseURL 拼接会出错
-	idx := strings.LastIndex(url, "/")
-	if idx < 0 {
-		return nil, errors.New("无效的 m3u8 url: " + url)
+// Select 从 MasterPlaylist 的多个码率中选择一个,返回nil表示没有合适的码率
+type Select func(vs []*Variant) *Variant
+
+// Media 获取 MediaPlaylist, MasterPlaylist 时按 sel 选择码率后获取对应的 MediaPlaylist,
+// 返回最终的地址,用于拼接相对地址
+func Media(url string, sel ...Select) (*m3u8.MediaPlaylist, *neturl.URL, error) {
+	s := Highest
+	if len(sel) > 0 && sel[0] != nil {
+		s = sel[0]
+	}
+
+	//MasterPlaylist 可能嵌套,限制层数避免循环引用
+	for i := 0; i < 3; i++ {
+		playlist, listType, base, err := fetch(url)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if listType == m3u8.MEDIA {
+			return playlist.(*m3u8.MediaPlaylist), base, nil
+		}
+
+		master := playlist.(*m3u8.MasterPlaylist)
+		vs := make([]*Variant, 0, len(master.Variants))
+		for _, v := range master.Variants {
+			//I帧码率只有关键帧,不能用于下载
+			if v != nil && !v.Iframe {
+				vs = append(vs, v)
+			}
+		}
+		if len(vs) == 0 {
+			return nil, nil, errors.New("MasterPlaylist 没有可用的码率")
+		}
+		v := s(vs)
+		if v == nil {
+			return nil, nil, errors.New("没有选择 MasterPlaylist 的码率")
+		}
+		url = Resolve(base, v.URI)
+	}
+
+	return nil, nil, errors.New("MasterPlaylist 嵌套层数过多: " + url)
+}
+
+// fetch 下载并解析 m3u8 文件,返回重定向后的地址
+func fetch(url string) (m3u8.Playlist, m3u8.ListType, *neturl.URL, error) {
+
+	// 校验 URL 必须是绝对地址，否则后续相对地址拼接会出错
+	u, err := neturl.Parse(url)
+	if err != nil || !u.IsAbs() {
+		return nil, 0, nil, errors.New("无效的 m3u8 url: " + url)
 	}
 
 	// 下载 m3u8 文件
 	resp, err := defaultClient.Get(url)
 	if err != nil {
-		return nil, err
+		return nil, 0, nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("m3u8 下载失败, status=%d", resp.StatusCode)
+		return nil, 0, nil, fmt.Errorf("m3u8 下载失败, status=%d", resp.StatusCode)
 	}
 
 	playlist, listType, err := m3u8.DecodeFrom(resp.Body, true)
 	if err != nil {
-		return nil, err
+		return nil, 0, nil, err
 	}
 
-	if listType != m3u8.MEDIA {
-		return nil, errors.New("不是 MediaPlaylist（可能是 MasterPlaylist）")
-	}
+	return playlist, listType, resp.Request.URL, nil
+}
 
-	media := playlist.(*m3u8.MediaPlaylist)
+// Resolve 拼接相对地址
+func Resolve(base *neturl.URL, uri string) string {
+	u, err := neturl.Parse(uri)
+	if err != nil {
+		return uri
+	}
+	return base.ResolveReference(u).String()
+}
 
-	baseURL := url[:idx+1]
+// Highest 选择最高码率
+func Highest(vs []*Variant) *Variant {
+	var r *Variant
+	for _, v := range vs {
+		if r == nil || v.Bandwidth > r.Bandwidth {
+			r = v
+		}
+	}
+	return r
+}
 
-	ls := make([]string, 0, len(media.Segments))
+// Lowest 选择最低码率
+func Lowest(vs []*Variant) *Variant {
+	var r *Variant
+	for _, v := range vs {
+		if r == nil || v.Bandwidth < r.Bandwidth {
+			r = v
+		}
+	}
+	return r
+}
 
-	for _, segment := range media.Segments {
-		if segment == nil {
-			continue
+// Resolution 选择分辨率最接近的码率,分辨率相同时选择码率高的,都没有分辨率时选择最高码率
+func Resolution(width, height int) Select {
+	return func(vs []*Variant) *Variant {
+		var (
+			r    *Variant
+			diff = -1
+		)
+		for _, v := range vs {
+			w, h, ok := ParseResolution(v.Resolution)
+			if !ok {
+				continue
+			}
+			d := abs(w-width) + abs(h-height)
+			if diff < 0 || d < diff || (d == diff && v.Bandwidth > r.Bandwidth) {
+				r, diff = v, d
+			}
 		}
-		tsURL := segment.URI
-		if !strings.HasPrefix(tsURL, "http") {
-			tsURL = baseURL + tsURL
+		if r == nil {
+			return Highest(vs)
 		}
+		return r
+	}
+}
 
-		ls = append(ls, tsURL)
+// ParseResolution 解析分辨率,例 1280x720
+func ParseResolution(s string) (width, height int, ok bool) {
+	ws, hs, ok := strings.Cut(strings.ToLower(s), "x")
+	if !ok {
+		return 0, 0, false
 	}
+	width, err1 := strconv.Atoi(strings.TrimSpace(ws))
+	height, err2 := strconv.Atoi(strings.TrimSpace(hs))
+	return width, height, err1 == nil && err2 == nil
+}
 
-	return ls, nil
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
 }
 
-func MergeByFFmpeg(dir, output string) error {
+// MergeByFFmpeg 使用ffmpeg按顺序合并dir下的分片文件,names为分片的文件名(相对dir)
+func MergeByFFmpeg(dir string, names []string, output string) error {
 	lsFilename := filepath.Join(dir, "ts_list.txt")
 	lsFilename = strings.ReplaceAll(lsFilename, "\\", "/")
 	file, err := os.Create(lsFilename)
@@ -73,23 +174,23 @@ func MergeByFFmpeg(dir, output string) error {
 		return err
 	}
 	defer os.Remove(lsFilename)
-	defer file.Close()
-
-	es, err := os.ReadDir(dir)
-	if err != nil {
-		return err
-	}
 
-	for _, e := range es {
-		info, err := e.Info()
-		if err != nil {
+	for _, name := range names {
+		//单引号需要转义
+		name = strings.ReplaceAll(name, "'", `'\''`)
+		if _, err = file.WriteString("file '" + name + "'\r\n"); err != nil {
+			file.Close()
 			return err
 		}
-		if !info.IsDir() && strings.HasSuffix(info.Name(), ".ts") {
-			_, err = file.WriteString("file '" + info.Name() + "'\r\n")
-		}
+	}
+	if err = file.Close(); err != nil {
+		return err
 	}
 
-	cmd := fmt.Sprintf("ffmpeg -y -f concat -i %s -c copy %s", lsFilename, output)
-	return shell.Run(cmd)
+	//直接执行ffmpeg,不经过shell,避免路径中的特殊字符被当作命令执行
+	cmd := exec.Command("ffmpeg", "-y", "-f", "concat", "-safe", "0", "-i", lsFilename, "-c", "copy", output)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("ffmpeg合并失败: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
 }
@@ -0,0 +1,158 @@
+package m3u8
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/grafov/m3u8"
+)
+
+// TestMergeByFFmpegNoShell 输出路径中的shell语法不能被执行
+func TestMergeByFFmpegNoShell(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake ffmpeg is a shell script")
+	}
+	bin := t.TempDir()
+	//假的ffmpeg,记录最后一个参数(输出路径)
+	script := "#!/bin/sh\nfor a; do last=$a; done\nprintf '%s' \"$last\" > '" + filepath.Join(bin, "output") + "'\n"
+	if err := os.WriteFile(filepath.Join(bin, "ffmpeg"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin)
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.ts"), []byte("a"), 0o644)
+	output := filepath.Join(dir, "out$(touch "+filepath.Join(dir, "pwned")+").ts")
+	if err := MergeByFFmpeg(dir, []string{"a.ts"}, output); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "pwned")); !os.IsNotExist(err) {
+		t.Fatal("shell syntax in output was executed")
+	}
+	if bs, _ := os.ReadFile(filepath.Join(bin, "output")); string(bs) != output {
+		t.Fatalf("output arg: got %q, want %q", bs, output)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	vs := []*Variant{
+		{URI: "a", VariantParams: m3u8.VariantParams{Bandwidth: 800000, Resolution: "640x360"}},
+		{URI: "b", VariantParams: m3u8.VariantParams{Bandwidth: 2800000, Resolution: "1920x1080"}},
+		{URI: "c", VariantParams: m3u8.VariantParams{Bandwidth: 1400000, Resolution: "1280x720"}},
+		{URI: "d", VariantParams: m3u8.VariantParams{Bandwidth: 1600000, Resolution: "1280X720"}},
+		{URI: "e", VariantParams: m3u8.VariantParams{Bandwidth: 100000}},
+	}
+	for _, v := range []struct {
+		name string
+		sel  Select
+		vs   []*Variant
+		want string
+	}{
+		{"highest", Highest, vs, "b"},
+		{"lowest", Lowest, vs, "e"},
+		{"exact", Resolution(640, 360), vs, "a"},
+		{"same resolution", Resolution(1280, 720), vs, "d"},
+		{"nearest", Resolution(1000, 600), vs, "d"},
+		{"above", Resolution(3840, 2160), vs, "b"},
+		{"no resolution", Resolution(1280, 720), vs[4:], "e"},
+		{"no resolution highest", Resolution(1280, 720), []*Variant{vs[4], {URI: "f", VariantParams: m3u8.VariantParams{Bandwidth: 200000}}}, "f"},
+	} {
+		got := v.sel(v.vs)
+		if got == nil || got.URI != v.want {
+			t.Errorf("%s: got %+v, want %s", v.name, got, v.want)
+		}
+	}
+	if Highest(nil) != nil || Lowest(nil) != nil || Resolution(1, 1)(nil) != nil {
+		t.Error("empty: want nil")
+	}
+}
+
+func TestParseResolution(t *testing.T) {
+	for _, v := range []struct {
+		s    string
+		w, h int
+		ok   bool
+	}{
+		{"1280x720", 1280, 720, true},
+		{"1920X1080", 1920, 1080, true},
+		{" 640 x 360 ", 640, 360, true},
+		{"", 0, 0, false},
+		{"720p", 0, 0, false},
+		{"axb", 0, 0, false},
+	} {
+		w, h, ok := ParseResolution(v.s)
+		if ok != v.ok || (ok && (w != v.w || h != v.h)) {
+			t.Errorf("ParseResolution(%q): got %d,%d,%v", v.s, w, h, ok)
+		}
+	}
+}
+
+// TestMediaMaster MasterPlaylist 按选择的码率获取 MediaPlaylist ,跳过I帧码率,相对地址按重定向后的地址拼接
+func TestMediaMaster(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/live/master.m3u8", http.StatusFound)
+	})
+	mux.HandleFunc("/live/master.m3u8", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `#EXTM3U
+#EXT-X-STREAM-INF:BANDWIDTH=800000,RESOLUTION=640x360
+low/index.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=2800000,RESOLUTION=1920x1080
+high/index.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=1400000,RESOLUTION=1280x720
+mid/index.m3u8
+#EXT-X-I-FRAME-STREAM-INF:BANDWIDTH=9000000,RESOLUTION=1920x1080,URI="iframe/index.m3u8"
+`)
+	})
+	for _, name := range []string{"low", "mid", "high", "iframe"} {
+		mux.HandleFunc("/live/"+name+"/index.m3u8", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "#EXTM3U\n#EXT-X-TARGETDURATION:2\n#EXT-X-MEDIA-SEQUENCE:5\n#EXTINF:2,\n%s0.ts\n#EXTINF:2,\n/abs/%s1.ts\n#EXT-X-ENDLIST\n", name, name)
+		})
+	}
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	for _, v := range []struct {
+		name string
+		url  string
+		sel  []Select
+		want string
+	}{
+		{"default highest", "/live/master.m3u8", nil, "high"},
+		{"nil select", "/live/master.m3u8", []Select{nil}, "high"},
+		{"lowest", "/live/master.m3u8", []Select{Lowest}, "low"},
+		{"resolution", "/live/master.m3u8", []Select{Resolution(1280, 720)}, "mid"},
+		{"redirect", "/redirect", []Select{Lowest}, "low"},
+	} {
+		media, base, err := Media(srv.URL+v.url, v.sel...)
+		if err != nil {
+			t.Fatalf("%s: %v", v.name, err)
+		}
+		if want := srv.URL + "/live/" + v.want + "/index.m3u8"; base.String() != want {
+			t.Fatalf("%s: base %s, want %s", v.name, base, want)
+		}
+		ls, err := Segments(media, base)
+		if err != nil {
+			t.Fatalf("%s: %v", v.name, err)
+		}
+		if len(ls) != 2 ||
+			ls[0].URL != srv.URL+"/live/"+v.want+"/"+v.want+"0.ts" || ls[0].Sequence != 5 ||
+			ls[1].URL != srv.URL+"/abs/"+v.want+"1.ts" || ls[1].Sequence != 6 {
+			t.Fatalf("%s: segments %+v %+v", v.name, ls[0], ls[1])
+		}
+	}
+
+	//没有选择码率
+	if _, _, err := Media(srv.URL+"/live/master.m3u8", func(vs []*Variant) *Variant { return nil }); err == nil {
+		t.Fatal("nil variant: no error")
+	}
+	//相对地址
+	if _, _, err := Media("/live/master.m3u8"); err == nil {
+		t.Fatal("relative url: no error")
+	}
+}
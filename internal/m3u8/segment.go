@@ -0,0 +1,84 @@
+package m3u8
+
+import (
+	"encoding/hex"
+	"errors"
+	neturl "net/url"
+	"strings"
+
+	"github.com/grafov/m3u8"
+)
+
+// Segment 分片
+type Segment struct {
+	URL      string  //分片的地址
+	Sequence uint64  //媒体序号, EXT-X-MEDIA-SEQUENCE 加上分片的下标
+	Duration float64 //时长(秒)
+	Key      *Key    //加密的密钥,nil表示不加密
+}
+
+// Key 分片加密的密钥, EXT-X-KEY
+type Key struct {
+	Method string //加密方式,目前只支持 AES-128
+	URI    string //密钥的地址
+	IV     []byte //初始向量,nil表示根据媒体序号生成
+}
+
+// DecodeSegments 解析 m3u8 的分片, MasterPlaylist 时按 sel 选择码率,默认选择最高码率
+func DecodeSegments(url string, sel ...Select) ([]*Segment, error) {
+	media, base, err := Media(url, sel...)
+	if err != nil {
+		return nil, err
+	}
+	return Segments(media, base)
+}
+
+// Segments 获取 MediaPlaylist 的分片, EXT-X-KEY 对之后的分片都有效,直到下一个 EXT-X-KEY
+func Segments(media *m3u8.MediaPlaylist, base *neturl.URL) ([]*Segment, error) {
+	var (
+		key *Key
+		ls  = make([]*Segment, 0, len(media.Segments))
+	)
+	for i, segment := range media.Segments {
+		if segment == nil {
+			continue
+		}
+		if segment.Key != nil {
+			k, err := newKey(segment.Key, base)
+			if err != nil {
+				return nil, err
+			}
+			key = k
+		}
+		ls = append(ls, &Segment{
+			URL:      Resolve(base, segment.URI),
+			Sequence: media.SeqNo + uint64(i),
+			Duration: segment.Duration,
+			Key:      key,
+		})
+	}
+	return ls, nil
+}
+
+// newKey 解析密钥,METHOD=NONE 时返回nil
+func newKey(k *m3u8.Key, base *neturl.URL) (*Key, error) {
+	if k.Method == "" || strings.EqualFold(k.Method, "NONE") {
+		return nil, nil
+	}
+	if k.URI == "" {
+		return nil, errors.New("EXT-X-KEY 缺少 URI")
+	}
+	key := &Key{
+		Method: strings.ToUpper(k.Method),
+		URI:    Resolve(base, k.URI),
+	}
+	if k.IV != "" {
+		s := strings.TrimPrefix(strings.TrimPrefix(k.IV, "0x"), "0X")
+		iv, err := hex.DecodeString(s)
+		if err != nil || len(iv) != 16 {
+			return nil, errors.New("EXT-X-KEY 无效的 IV: " + k.IV)
+		}
+		key.IV = iv
+	}
+	return key, nil
+}
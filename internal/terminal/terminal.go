@@ -0,0 +1,119 @@
+package terminal
+
+import (
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/mattn/go-isatty"
+	"golang.org/x/text/width"
+)
+
+// cache 终端宽度的缓存,终端大小变化时清除
+var cache sync.Map
+
+// GetWidth 获取终端的宽度(列数),fd不是终端时返回错误
+func GetWidth(fd uintptr) (int, error) {
+	watch()
+	if v, ok := cache.Load(fd); ok {
+		return v.(int), nil
+	}
+	w, err := getWidth(fd)
+	if err != nil {
+		return 0, err
+	}
+	if cacheable {
+		cache.Store(fd, w)
+	}
+	return w, nil
+}
+
+// Width 字符串的显示宽度,忽略ANSI控制符,全角字符占2列
+func Width(s string) int {
+	n := 0
+	for i := 0; i < len(s); {
+		if l := escapeLen(s[i:]); l > 0 {
+			i += l
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		n += runeWidth(r)
+		i += size
+	}
+	return n
+}
+
+// Truncate 截断字符串到指定的显示宽度,保留ANSI控制符,截断后重置颜色
+func Truncate(s string, w int) string {
+	if Width(s) <= w {
+		return s
+	}
+	var b strings.Builder
+	n := 0
+	escaped := false
+	for i := 0; i < len(s); {
+		if l := escapeLen(s[i:]); l > 0 {
+			b.WriteString(s[i : i+l])
+			escaped = true
+			i += l
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if n+runeWidth(r) > w {
+			break
+		}
+		n += runeWidth(r)
+		b.WriteString(s[i : i+size])
+		i += size
+	}
+	if escaped {
+		b.WriteString("\033[0m")
+	}
+	return b.String()
+}
+
+// StripANSI 去除ANSI控制符
+func StripANSI(s string) string {
+	if !strings.Contains(s, "\033") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		if l := escapeLen(s[i:]); l > 0 {
+			i += l
+			continue
+		}
+		b.WriteByte(s[i])
+		i++
+	}
+	return b.String()
+}
+
+// escapeLen ANSI控制符(CSI)的长度,例 \033[0m ,不是控制符返回0
+func escapeLen(s string) int {
+	if len(s) < 2 || s[0] != '\033' || s[1] != '[' {
+		return 0
+	}
+	for i := 2; i < len(s); i++ {
+		if s[i] >= 0x40 && s[i] <= 0x7E {
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+func runeWidth(r rune) int {
+	if r < 0x20 || r == 0x7F {
+		return 0
+	}
+	switch width.LookupRune(r).Kind() {
+	case width.EastAsianWide, width.EastAsianFullwidth:
+		return 2
+	}
+	return 1
+}
+
+// IsTerminal fd是否是终端
+func IsTerminal(fd uintptr) bool {
+	return isatty.IsTerminal(fd) || isatty.IsCygwinTerminal(fd)
+}
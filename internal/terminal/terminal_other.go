@@ -0,0 +1,13 @@
+//go:build !unix && !windows
+
+package terminal
+
+import "errors"
+
+const cacheable = false
+
+func watch() {}
+
+func getWidth(fd uintptr) (int, error) {
+	return 0, errors.New("不支持获取终端宽度")
+}
@@ -0,0 +1,64 @@
+package terminal
+
+import "testing"
+
+func TestWidth(t *testing.T) {
+	for _, v := range []struct {
+		s    string
+		want int
+	}{
+		{"", 0},
+		{"abc", 3},
+		{"下载", 4},
+		{"a下b", 4},
+		{"ｆｕｌｌ", 8},
+		{"\033[31mred\033[0m", 3},
+		{"\033[1;32m成功\033[0m ✓", 6},
+		{"\r\033[K50%", 3},
+	} {
+		if got := Width(v.s); got != v.want {
+			t.Errorf("Width(%q): got %d, want %d", v.s, got, v.want)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	for _, v := range []struct {
+		s    string
+		w    int
+		want string
+	}{
+		{"abc", 5, "abc"},
+		{"abcdef", 3, "abc"},
+		{"abc", 0, ""},
+		{"下载中", 4, "下载"},
+		{"下载中", 5, "下载"}, //宽字符放不下时不截断一半
+		{"a下载", 2, "a"},
+		{"\033[31mred\033[0m", 3, "\033[31mred\033[0m"},
+		{"\033[31mredred\033[0m", 3, "\033[31mred\033[0m"},
+		{"\033[32m下载\033[0m中", 3, "\033[32m下\033[0m"},
+	} {
+		got := Truncate(v.s, v.w)
+		if got != v.want {
+			t.Errorf("Truncate(%q, %d): got %q, want %q", v.s, v.w, got, v.want)
+		}
+		if Width(got) > v.w {
+			t.Errorf("Truncate(%q, %d): width %d", v.s, v.w, Width(got))
+		}
+	}
+}
+
+func TestStripANSI(t *testing.T) {
+	for _, v := range []struct {
+		s    string
+		want string
+	}{
+		{"abc", "abc"},
+		{"\033[31m下载\033[0m", "下载"},
+		{"\r\033[K\033[1;32m✓\033[0m", "\r✓"},
+	} {
+		if got := StripANSI(v.s); got != v.want {
+			t.Errorf("StripANSI(%q): got %q, want %q", v.s, got, v.want)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+//go:build unix
+
+package terminal
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// cacheable 终端大小变化时会收到SIGWINCH信号,可以缓存宽度
+const cacheable = true
+
+var watchOnce sync.Once
+
+// watch 监听终端大小变化(SIGWINCH),清除宽度缓存
+func watch() {
+	watchOnce.Do(func() {
+		c := make(chan os.Signal, 1)
+		signal.Notify(c, syscall.SIGWINCH)
+		go func() {
+			for range c {
+				cache.Clear()
+			}
+		}()
+	})
+}
+
+func getWidth(fd uintptr) (int, error) {
+	ws, err := unix.IoctlGetWinsize(int(fd), unix.TIOCGWINSZ)
+	if err != nil {
+		return 0, err
+	}
+	return int(ws.Col), nil
+}
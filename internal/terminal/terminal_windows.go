@@ -0,0 +1,18 @@
+//go:build windows
+
+package terminal
+
+import "golang.org/x/sys/windows"
+
+// cacheable windows没有大小变化的信号,每次都重新获取
+const cacheable = false
+
+func watch() {}
+
+func getWidth(fd uintptr) (int, error) {
+	var info windows.ConsoleScreenBufferInfo
+	if err := windows.GetConsoleScreenBufferInfo(windows.Handle(fd), &info); err != nil {
+		return 0, err
+	}
+	return int(info.Window.Right-info.Window.Left) + 1, nil
+}
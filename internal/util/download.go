@@ -0,0 +1,301 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Progress 下载进度回调,为nil的字段会忽略
+type Progress struct {
+	SetTotal   func(total int64)   //设置总大小,未知时为-1
+	SetCurrent func(current int64) //设置已下载的大小,断点续传时使用
+	Add        func(n int64)       //每次写入的大小
+}
+
+func (this *Progress) setTotal(total int64) {
+	if this != nil && this.SetTotal != nil {
+		this.SetTotal(total)
+	}
+}
+
+func (this *Progress) setCurrent(current int64) {
+	if this != nil && this.SetCurrent != nil {
+		this.SetCurrent(current)
+	}
+}
+
+func (this *Progress) add(n int64) {
+	if this != nil && this.Add != nil {
+		this.Add(n)
+	}
+}
+
+// progressWriter 写入时回调进度
+type progressWriter struct {
+	io.Writer
+	*Progress
+}
+
+func (this *progressWriter) Write(p []byte) (int, error) {
+	n, err := this.Writer.Write(p)
+	this.add(int64(n))
+	return n, err
+}
+
+// GetToFile 下载到文件,先写入到 filename.downloading ,完成后再重命名
+// 如果 filename.downloading 已存在,则使用Range请求断点续传,
+// 并通过If-Range校验文件是否变化(ETag/Last-Modified),服务端不支持或文件已变化时重新下载
+// 返回文件的总大小
+func (this *Client) GetToFile(url string, filename string, p ...*Progress) (int64, error) {
+	var progress *Progress
+	if len(p) > 0 {
+		progress = p[0]
+	}
+
+	tempFilename := filename + ".downloading"
+	metaFilename := tempFilename + ".meta"
+
+	//已下载的大小和校验值
+	offset := int64(0)
+	validator := ""
+	if stat, err := os.Stat(tempFilename); err == nil && stat.Size() > 0 {
+		if bs, err := os.ReadFile(metaFilename); err == nil && len(bs) > 0 {
+			offset = stat.Size()
+			validator = string(bs)
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		req.Header.Set("If-Range", validator)
+	}
+
+	resp, err := this.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	var w *os.File
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && offset > 0 && contentRangeStart(resp) == offset:
+		//断点续传
+		if resp.ContentLength >= 0 {
+			progress.setTotal(offset + resp.ContentLength)
+		} else {
+			progress.setTotal(-1)
+		}
+		progress.setCurrent(offset)
+		w, err = os.OpenFile(tempFilename, os.O_WRONLY|os.O_APPEND, 0666)
+
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0 && contentRangeTotal(resp) == offset:
+		//已经下载完成,只是还没有重命名(例如重命名前进程退出)
+		resp.Body.Close()
+		progress.setTotal(offset)
+		progress.setCurrent(offset)
+		os.Remove(metaFilename)
+		return offset, os.Rename(tempFilename, filename)
+
+	case (resp.StatusCode == http.StatusPartialContent || resp.StatusCode == http.StatusRequestedRangeNotSatisfiable) && offset > 0:
+		//已下载的部分无效或者返回的范围不对,删除后重新下载
+		os.Remove(tempFilename)
+		os.Remove(metaFilename)
+		resp.Body.Close()
+		return this.GetToFile(url, filename, p...)
+
+	case resp.StatusCode >= 200 && resp.StatusCode < 300 && resp.StatusCode != http.StatusPartialContent:
+		//服务端不支持Range,或者文件已变化,重新下载
+		offset = 0
+		progress.setTotal(resp.ContentLength)
+		if v := responseValidator(resp); v != "" {
+			if err = os.WriteFile(metaFilename, []byte(v), 0666); err != nil {
+				return 0, err
+			}
+		} else {
+			os.Remove(metaFilename)
+		}
+		w, err = os.Create(tempFilename)
+
+	default:
+		return 0, fmt.Errorf("下载失败, status=%d", resp.StatusCode)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := io.Copy(&progressWriter{Writer: w, Progress: progress}, resp.Body)
+	if err != nil {
+		w.Close()
+		return offset + n, err
+	}
+	w.Close()
+
+	// 等待文件写入完成,否则有可能会报错
+	<-time.After(time.Millisecond * 100)
+	os.Remove(metaFilename)
+	return offset + n, os.Rename(tempFilename, filename)
+}
+
+// responseValidator 获取用于If-Range的校验值,弱ETag不能用于If-Range
+func responseValidator(resp *http.Response) string {
+	if etag := resp.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return resp.Header.Get("Last-Modified")
+}
+
+// contentRangeStart 解析Content-Range的起始位置,例 bytes 100-199/200
+func contentRangeStart(resp *http.Response) int64 {
+	var start, end int64
+	var total string
+	cr := resp.Header.Get("Content-Range")
+	if _, err := fmt.Sscanf(cr, "bytes %d-%d/%s", &start, &end, &total); err != nil {
+		return -1
+	}
+	return start
+}
+
+// contentRangeTotal 解析Content-Range的总大小,例 bytes */200 ,未知时返回-1
+func contentRangeTotal(resp *http.Response) int64 {
+	cr := resp.Header.Get("Content-Range")
+	i := strings.LastIndex(cr, "/")
+	if i < 0 {
+		return -1
+	}
+	total, err := strconv.ParseInt(strings.TrimSpace(cr[i+1:]), 10, 64)
+	if err != nil {
+		return -1
+	}
+	return total
+}
+
+// GetToFileParallel 多连接分段下载到文件,先通过HEAD获取文件大小,
+// 再按字节范围分成n段并发下载,使用WriteAt写入预分配的文件
+// 服务端不支持Range(Accept-Ranges)或者未知大小时,退化成单连接下载,
+// 存在单连接下载未完成的部分(filename.downloading)时,也使用单连接断点续传
+func (this *Client) GetToFileParallel(url string, filename string, n int, p ...*Progress) (int64, error) {
+	var progress *Progress
+	if len(p) > 0 {
+		progress = p[0]
+	}
+
+	if n <= 1 || resumable(filename) {
+		//存在可以断点续传的部分时,使用单连接续传,避免丢弃已下载的内容
+		return this.GetToFile(url, filename, p...)
+	}
+
+	resp, err := this.Head(url)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	size := resp.ContentLength
+	if resp.StatusCode != http.StatusOK || size <= 0 || resp.Header.Get("Accept-Ranges") != "bytes" {
+		return this.GetToFile(url, filename, p...)
+	}
+	validator := responseValidator(resp)
+
+	tempFilename := filename + ".downloading"
+	os.Remove(tempFilename + ".meta")
+	w, err := os.Create(tempFilename)
+	if err != nil {
+		return 0, err
+	}
+	if err = w.Truncate(size); err != nil {
+		w.Close()
+		return 0, err
+	}
+
+	progress.setTotal(size)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if int64(n) > size {
+		n = int(size)
+	}
+	chunk := size / int64(n)
+	var (
+		wg   sync.WaitGroup
+		once sync.Once
+		e    error
+	)
+	for i := 0; i < n; i++ {
+		start := int64(i) * chunk
+		end := start + chunk - 1
+		if i == n-1 {
+			//最后一段包含剩余的全部
+			end = size - 1
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := this.getRange(ctx, url, validator, start, end, w, progress); err != nil {
+				once.Do(func() {
+					e = err
+					cancel()
+				})
+			}
+		}()
+	}
+	wg.Wait()
+	w.Close()
+	if e != nil {
+		return 0, e
+	}
+
+	// 等待文件写入完成,否则有可能会报错
+	<-time.After(time.Millisecond * 100)
+	return size, os.Rename(tempFilename, filename)
+}
+
+// resumable 是否存在可以断点续传的部分,即 GetToFile 未完成的临时文件和校验值
+func resumable(filename string) bool {
+	tempFilename := filename + ".downloading"
+	stat, err := os.Stat(tempFilename)
+	if err != nil || stat.Size() == 0 {
+		return false
+	}
+	bs, err := os.ReadFile(tempFilename + ".meta")
+	return err == nil && len(bs) > 0
+}
+
+// getRange 下载指定范围[start,end]的数据,写入到w的对应位置
+func (this *Client) getRange(ctx context.Context, url, validator string, start, end int64, w io.WriterAt, progress *Progress) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end))
+	if validator != "" {
+		req.Header.Set("If-Range", validator)
+	}
+	resp, err := this.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent || contentRangeStart(resp) != start {
+		return fmt.Errorf("分段下载失败, status=%d", resp.StatusCode)
+	}
+	sw := io.NewOffsetWriter(w, start)
+	n, err := io.Copy(&progressWriter{Writer: sw, Progress: progress}, io.LimitReader(resp.Body, end-start+1))
+	if err != nil {
+		return err
+	}
+	if n != end-start+1 {
+		return fmt.Errorf("分段下载不完整, 预期%d字节, 得到%d字节", end-start+1, n)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package util
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestGetToFileCompleted 临时文件已经下载完成(重命名前进程退出),不应该重新下载
+func TestGetToFileCompleted(t *testing.T) {
+	data := bytes.Repeat([]byte("x"), 1000)
+	full := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Range") == "" {
+			full++
+		}
+		w.Header().Set("ETag", `"abc"`)
+		http.ServeContent(w, r, "f", time.Time{}, bytes.NewReader(data))
+	}))
+	defer s.Close()
+
+	filename := t.TempDir() + "/f"
+	os.WriteFile(filename+".downloading", data, 0666)
+	os.WriteFile(filename+".downloading.meta", []byte(`"abc"`), 0666)
+
+	n, err := NewClient().GetToFile(s.URL, filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len(data)) {
+		t.Fatalf("size: got %d, want %d", n, len(data))
+	}
+	if full != 0 {
+		t.Fatalf("downloaded again %d times", full)
+	}
+	if bs, err := os.ReadFile(filename); err != nil || !bytes.Equal(bs, data) {
+		t.Fatalf("file content mismatch: %v", err)
+	}
+	if _, err := os.Stat(filename + ".downloading"); !os.IsNotExist(err) {
+		t.Fatalf("temp file still exists: %v", err)
+	}
+}
+
+// TestGetToFileParallelResume 存在单连接未完成的部分时,分段下载应该续传而不是丢弃
+func TestGetToFileParallelResume(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 100)
+	var ranges []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			ranges = append(ranges, r.Header.Get("Range"))
+		}
+		w.Header().Set("ETag", `"abc"`)
+		http.ServeContent(w, r, "f", time.Time{}, bytes.NewReader(data))
+	}))
+	defer s.Close()
+
+	filename := t.TempDir() + "/f"
+	os.WriteFile(filename+".downloading", data[:400], 0666)
+	os.WriteFile(filename+".downloading.meta", []byte(`"abc"`), 0666)
+
+	if _, err := NewClient().GetToFileParallel(s.URL, filename, 4); err != nil {
+		t.Fatal(err)
+	}
+	if len(ranges) != 1 || ranges[0] != "bytes=400-" {
+		t.Fatalf("ranges: %q", ranges)
+	}
+	if bs, _ := os.ReadFile(filename); !bytes.Equal(bs, data) {
+		t.Fatal("file content mismatch")
+	}
+}
@@ -4,11 +4,9 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
-	"io"
 	"net"
 	"net/http"
 	"net/url"
-	"os"
 	"time"
 
 	"golang.org/x/net/proxy"
@@ -86,25 +84,3 @@ func (this *Client) Dial(network, addr string) (net.Conn, error) {
 	}
 	return d.Dial(network, addr)
 }
-
-func (this *Client) GetToFile(url string, filename string) (int64, error) {
-	resp, err := this.Get(url)
-	if err != nil {
-		return 0, err
-	}
-	defer resp.Body.Close()
-	w, err := os.Create(filename + ".downloading")
-	if err != nil {
-		return 0, err
-	}
-	n, err := io.Copy(w, resp.Body)
-	if err != nil {
-		w.Close()
-		return n, err
-	}
-	w.Close()
-
-	// 等待文件写入完成,否则有可能会报错
-	<-time.After(time.Millisecond * 100)
-	return n, os.Rename(filename+".downloading", filename)
-}
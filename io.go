@@ -0,0 +1,173 @@
+package bar
+
+import (
+	"io"
+)
+
+// Copy 复制数据,按读取的字节增加进度,不会结束进度条(可以多次复制到同一个进度条),
+// 需要时调用 Finish 或 Fail 结束,见包级的 Copy
+func (this *Bar) Copy(w io.Writer, r io.Reader) (int64, error) {
+	return this.CopyN(w, r, 32<<10)
+}
+
+// CopyN 复制数据,bufSize为缓存的大小,按读取的字节增加进度
+func (this *Bar) CopyN(w io.Writer, r io.Reader, bufSize int64) (int64, error) {
+	if bufSize <= 0 {
+		bufSize = 32 << 10
+	}
+	return io.CopyBuffer(w, this.NewReader(r), make([]byte, bufSize))
+}
+
+// NewReader 按读取的字节增加进度
+func (this *Bar) NewReader(r io.Reader) *Reader {
+	return &Reader{Reader: r, Bar: this}
+}
+
+// NewWriter 按写入的字节增加进度
+func (this *Bar) NewWriter(w io.Writer) *Writer {
+	return &Writer{Writer: w, Bar: this}
+}
+
+// NewReadCloser 按读取的字节增加进度,关闭时结束进度条,
+// 读取完成时记为成功,关闭失败时记为失败,未读取完成时记为中止
+func (this *Bar) NewReadCloser(rc io.ReadCloser) *ReadCloser {
+	return &ReadCloser{ReadCloser: rc, Bar: this}
+}
+
+// NewReadSeeker 以读取的位置作为进度,Seek后从新的位置继续计算,
+// 只Seek不读取时进度不变(例如Seek到末尾获取大小)
+func (this *Bar) NewReadSeeker(rs io.ReadSeeker) *ReadSeeker {
+	return &ReadSeeker{ReadSeeker: rs, Bar: this}
+}
+
+// NewReaderAt 按读取的字节增加进度,用于随机读取,重复读取的部分会重复计算
+func (this *Bar) NewReaderAt(r io.ReaderAt) *ReaderAt {
+	return &ReaderAt{ReaderAt: r, Bar: this}
+}
+
+// NewWriterAt 按写入的字节增加进度,用于随机写入(例如分段下载),重复写入的部分会重复计算
+func (this *Bar) NewWriterAt(w io.WriterAt) *WriterAt {
+	return &WriterAt{WriterAt: w, Bar: this}
+}
+
+// touch 标记需要刷新,由渲染循环按刷新频率刷新,完成时立即刷新最后一帧
+func (this *Bar) touch() {
+	if this.complete() {
+		this.Flush()
+		return
+	}
+	this.run()
+}
+
+type Reader struct {
+	io.Reader
+	*Bar
+}
+
+func (this *Reader) Read(p []byte) (n int, err error) {
+	n, err = this.Reader.Read(p)
+	if n > 0 {
+		this.Bar.Add(int64(n))
+		this.Bar.touch()
+	}
+	return
+}
+
+type Writer struct {
+	io.Writer
+	*Bar
+}
+
+func (this *Writer) Write(p []byte) (n int, err error) {
+	n, err = this.Writer.Write(p)
+	if n > 0 {
+		this.Bar.Add(int64(n))
+		this.Bar.touch()
+	}
+	return
+}
+
+type ReadCloser struct {
+	io.ReadCloser
+	*Bar
+	eof bool //是否读取完成
+}
+
+func (this *ReadCloser) Read(p []byte) (n int, err error) {
+	n, err = this.ReadCloser.Read(p)
+	if n > 0 {
+		this.Bar.Add(int64(n))
+		this.Bar.touch()
+	}
+	if err == io.EOF {
+		this.eof = true
+	}
+	return
+}
+
+// Close 关闭并结束进度条
+func (this *ReadCloser) Close() error {
+	err := this.ReadCloser.Close()
+	switch {
+	case err != nil:
+		this.Bar.Fail(err)
+	case this.eof || this.Bar.complete():
+		this.Bar.Finish()
+	default:
+		this.Bar.Abort()
+	}
+	return err
+}
+
+type ReadSeeker struct {
+	io.ReadSeeker
+	*Bar
+	pos int64 //当前读取的位置
+}
+
+func (this *ReadSeeker) Read(p []byte) (n int, err error) {
+	n, err = this.ReadSeeker.Read(p)
+	this.pos += int64(n)
+	if n > 0 {
+		this.Bar.SetCurrent(this.pos)
+		this.Bar.touch()
+	}
+	return
+}
+
+// Seek 移动读取的位置,下次读取时从新的位置计算进度
+func (this *ReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	pos, err := this.ReadSeeker.Seek(offset, whence)
+	if err == nil {
+		this.pos = pos
+	}
+	return pos, err
+}
+
+type ReaderAt struct {
+	io.ReaderAt
+	*Bar
+}
+
+func (this *ReaderAt) ReadAt(p []byte, off int64) (n int, err error) {
+	n, err = this.ReaderAt.ReadAt(p, off)
+	if n > 0 {
+		this.Bar.Add(int64(n))
+		this.Bar.touch()
+	}
+	return
+}
+
+type WriterAt struct {
+	io.WriterAt
+	*Bar
+}
+
+func (this *WriterAt) WriteAt(p []byte, off int64) (n int, err error) {
+	n, err = this.WriterAt.WriteAt(p, off)
+	if n > 0 {
+		this.Bar.Add(int64(n))
+		this.Bar.touch()
+	}
+	return
+}
@@ -0,0 +1,32 @@
+package bar_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/injoyai/bar"
+)
+
+// TestCopy 包级的Copy读取完成或出错时结束进度条,不留下渲染协程
+func TestCopy(t *testing.T) {
+	//第一次会启动监听终端大小的全局协程,之后再计数
+	bar.Copy(io.Discard, strings.NewReader("a"), 1)
+	base := runtime.NumGoroutine()
+	for i := 0; i < 10; i++ {
+		var w bytes.Buffer
+		n, err := bar.Copy(&w, strings.NewReader(strings.Repeat("a", 100<<10)), 0)
+		if err != nil || n != 100<<10 || w.Len() != 100<<10 {
+			t.Fatalf("copy: n=%d, err=%v", n, err)
+		}
+	}
+	e := errors.New("断开")
+	if _, err := bar.Copy(io.Discard, io.MultiReader(strings.NewReader("abc"), iotest.ErrReader(e)), 10); !errors.Is(err, e) {
+		t.Fatalf("copy: err=%v", err)
+	}
+	waitGoroutines(t, base)
+}
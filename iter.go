@@ -0,0 +1,67 @@
+package bar
+
+import (
+	"iter"
+)
+
+// Seq 遍历时显示进度,每处理完一个元素前进1,total<=0表示总数量未知,
+// 遍历完成时记为成功,提前退出时记为中止,例 for v := range bar.Seq(seq, 100) {}
+func Seq[T any](seq iter.Seq[T], total int64, op ...Option) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		b := newIter(total, op...)
+		for v := range seq {
+			if !yield(v) {
+				b.Abort()
+				return
+			}
+			b.Add(1).Flush()
+		}
+		b.Finish()
+	}
+}
+
+// Seq2 同 Seq ,例 for k, v := range bar.Seq2(seq, 100) {}
+func Seq2[K, V any](seq iter.Seq2[K, V], total int64, op ...Option) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		b := newIter(total, op...)
+		for k, v := range seq {
+			if !yield(k, v) {
+				b.Abort()
+				return
+			}
+			b.Add(1).Flush()
+		}
+		b.Finish()
+	}
+}
+
+// Slice 遍历切片时显示进度,例 for i, v := range bar.Slice(ls) {}
+func Slice[T any](ls []T, op ...Option) iter.Seq2[int, T] {
+	return Seq2(func(yield func(int, T) bool) {
+		for i, v := range ls {
+			if !yield(i, v) {
+				return
+			}
+		}
+	}, int64(len(ls)), op...)
+}
+
+// Chan 遍历通道时显示进度,通道关闭时结束,total<=0表示总数量未知,例 for v := range bar.Chan(ch, 100) {}
+func Chan[T any](ch <-chan T, total int64, op ...Option) iter.Seq[T] {
+	return Seq(func(yield func(T) bool) {
+		for v := range ch {
+			if !yield(v) {
+				return
+			}
+		}
+	}, total, op...)
+}
+
+// newIter 新建遍历使用的进度条
+func newIter(total int64, op ...Option) *Bar {
+	return New(
+		WithTotal(total),
+		WithOption(op...),
+		WithFlush(),
+	)
+}
@@ -0,0 +1,113 @@
+package bar
+
+import (
+	"strings"
+
+	"github.com/injoyai/bar/internal/terminal"
+)
+
+// MinFlexWidth 弹性格式的最小宽度
+const MinFlexWidth = 10
+
+// Width 行宽,未设置时获取终端的宽度,writer不是终端或者不限制时返回0
+func (this *Bar) Width() int {
+	if this.width != 0 {
+		return max(this.width, 0)
+	}
+	if f, ok := this.writer.(interface{ Fd() uintptr }); ok {
+		if w, err := terminal.GetWidth(f.Fd()); err == nil {
+			return w
+		}
+	}
+	return 0
+}
+
+// layout 按行宽排版多个格式
+// 弹性格式(例 WithPlan 设置了 SetFlex)会填满其它格式剩余的宽度,
+// 剩余宽度不足时,从后往前省略非弹性格式(靠后的优先级低),弹性格式缩小到 MinFlexWidth ,
+// 结束的状态(例 ✗ 错误信息)显示在最后,优先于非弹性格式,排版时预留它的宽度
+func (this *Bar) layout(fs []Format, ls []string) string {
+
+	//第一遍渲染,弹性格式只做标记
+	var flex []int
+	for i, f := range fs {
+		this.flex, this.flexing = 0, false
+		ls[i] = f(this)
+		if this.flexing {
+			flex = append(flex, i)
+		}
+	}
+	defer func() { this.flex, this.flexing = 0, false }()
+
+	width := this.Width()
+	if width <= 0 {
+		//不限制宽度,弹性格式使用自身的宽度
+		for _, i := range flex {
+			this.flex = -1
+			ls[i] = fs[i](this)
+		}
+		return strings.Join(ls, this.formatSplit)
+	}
+
+	//计算非弹性格式的宽度,超出时从后往前省略,至少保留第一个
+	show := make([]bool, len(fs))
+	for i := range show {
+		show[i] = true
+	}
+	split := terminal.Width(this.formatSplit)
+	reserved := 0
+	if !this.ownAffix {
+		reserved += terminal.Width(this.prefix) + terminal.Width(this.suffix)
+	}
+	if state := this.stateText(); state != "" {
+		reserved += split + terminal.Width(state)
+	}
+	used := func() int {
+		n := reserved
+		count := 0
+		for i, v := range ls {
+			if !show[i] {
+				continue
+			}
+			if count > 0 {
+				n += split
+			}
+			count++
+			if !isFlex(flex, i) {
+				n += terminal.Width(v)
+			}
+		}
+		return n
+	}
+	for i := len(fs) - 1; i > 0 && used()+len(flex)*MinFlexWidth > width; i-- {
+		if !isFlex(flex, i) {
+			show[i] = false
+		}
+	}
+
+	//弹性格式平分剩余的宽度
+	if len(flex) > 0 {
+		per := max((width-used())/len(flex), MinFlexWidth)
+		for _, i := range flex {
+			this.flex = per
+			ls[i] = fs[i](this)
+		}
+	}
+
+	result := make([]string, 0, len(ls))
+	for i, v := range ls {
+		if show[i] {
+			result = append(result, v)
+		}
+	}
+	return strings.Join(result, this.formatSplit)
+}
+
+func isFlex(flex []int, i int) bool {
+	for _, v := range flex {
+		if v == i {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package bar_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/injoyai/bar"
+	"github.com/injoyai/bar/bartest"
+	"github.com/injoyai/bar/internal/terminal"
+)
+
+func TestLayout(t *testing.T) {
+	flex := bar.WithPlan(func(p *bar.Plan) { p.SetFlex() })
+	for _, v := range []struct {
+		name  string
+		width int
+		op    []bar.Option
+		want  string
+	}{
+		{
+			"unlimited", -1,
+			[]bar.Option{bar.WithFormat(bar.WithRateSize(), bar.WithText("abcdefghij"), bar.WithUsed())},
+			"50/100  abcdefghij  2s",
+		},
+		{
+			"fit", 22,
+			[]bar.Option{bar.WithFormat(bar.WithRateSize(), bar.WithText("abcdefghij"), bar.WithUsed())},
+			"50/100  abcdefghij  2s",
+		},
+		{
+			"elide last", 21,
+			[]bar.Option{bar.WithFormat(bar.WithRateSize(), bar.WithText("abcdefghij"), bar.WithUsed())},
+			"50/100  abcdefghij",
+		},
+		{
+			"elide from back", 10,
+			[]bar.Option{bar.WithFormat(bar.WithRateSize(), bar.WithText("abcdefghij"), bar.WithUsed())},
+			"50/100",
+		},
+		{
+			"truncate first", 4,
+			[]bar.Option{bar.WithFormat(bar.WithRateSize(), bar.WithUsed())},
+			"50/1",
+		},
+		{
+			"wide prefix", 17,
+			[]bar.Option{bar.WithPrefix("下载 "), bar.WithFormat(bar.WithRateSize(), bar.WithText("中文"), bar.WithUsed())},
+			"下载 50/100  中文",
+		},
+		{
+			"flex", 30,
+			[]bar.Option{bar.WithFormat(flex, bar.WithRateSize())},
+			"[##########          ]  50/100",
+		},
+		{
+			"flex elide", 20,
+			[]bar.Option{bar.WithFormat(flex, bar.WithRateSize(), bar.WithUsed())},
+			"[#####     ]  50/100",
+		},
+		{
+			"wide elide", 16,
+			[]bar.Option{bar.WithPrefix("下载 "), bar.WithFormat(bar.WithRateSize(), bar.WithText("中文"), bar.WithUsed())},
+			"下载 50/100",
+		},
+		{
+			"flex min", 12,
+			[]bar.Option{bar.WithFormat(flex, bar.WithRateSize(), bar.WithUsed())},
+			"[#####     ]",
+		},
+		{
+			"flex unlimited", -1,
+			[]bar.Option{bar.WithFormat(bar.WithPlan(func(p *bar.Plan) { p.SetFlex(); p.SetWidth(10) }), bar.WithRateSize())},
+			"[#####     ]  50/100",
+		},
+	} {
+		t.Run(v.name, func(t *testing.T) {
+			b, c, r := bartest.New(append([]bar.Option{bar.WithTotal(100), bar.WithWidth(v.width)}, v.op...)...)
+			c.Add(2 * time.Second)
+			b.Add(50).Flush()
+			got := r.Last()
+			if got != v.want {
+				t.Fatalf("got %q, want %q", got, v.want)
+			}
+			if v.width > 0 && terminal.Width(got) > v.width {
+				t.Fatalf("width %d > %d", terminal.Width(got), v.width)
+			}
+		})
+	}
+}
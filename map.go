@@ -0,0 +1,44 @@
+package bar
+
+import (
+	"context"
+	"fmt"
+)
+
+// Map 协程并发处理切片,limit为最大并发数量,<=0表示不限制(全部同时处理),每处理完一个元素前进1,
+// 返回的结果和输入的顺序一致,失败的元素结果为零值,错误为所有失败元素的 ItemError
+func Map[T, R any](ctx context.Context, items []T, limit int, f func(ctx context.Context, item T) (R, error), op ...Option) ([]R, error) {
+	result := make([]R, len(items))
+	if len(items) == 0 {
+		return result, ctx.Err()
+	}
+	if limit <= 0 {
+		limit = len(items)
+	}
+	c := NewCoroutineContext(ctx, len(items), limit, ErrContinue, op...)
+	for i, item := range items {
+		c.GoContext(func(ctx context.Context) error {
+			r, err := f(ctx, item)
+			if err != nil {
+				return &ItemError{Index: i, Err: err}
+			}
+			result[i] = r
+			return nil
+		})
+	}
+	return result, c.Wait()
+}
+
+// ItemError 元素处理失败的错误,Index为元素的下标
+type ItemError struct {
+	Index int
+	Err   error
+}
+
+func (this *ItemError) Error() string {
+	return fmt.Sprintf("第%d个元素: %v", this.Index, this.Err)
+}
+
+func (this *ItemError) Unwrap() error {
+	return this.Err
+}
@@ -0,0 +1,43 @@
+package bar_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/injoyai/bar"
+	"github.com/injoyai/bar/bartest"
+)
+
+func TestMap(t *testing.T) {
+	for _, limit := range []int{0, -1, 1, 2} {
+		_, _, r := bartest.New()
+		done := make(chan struct{})
+		var (
+			result []int
+			err    error
+		)
+		go func() {
+			defer close(done)
+			result, err = bar.Map(context.Background(), []string{"1", "x", "3"}, limit,
+				func(ctx context.Context, s string) (int, error) { return strconv.Atoi(s) },
+				bar.WithWriter(r),
+			)
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("limit %d: Map did not return", limit)
+		}
+
+		if len(result) != 3 || result[0] != 1 || result[1] != 0 || result[2] != 3 {
+			t.Fatalf("limit %d: result %v", limit, result)
+		}
+		var ie *bar.ItemError
+		if !errors.As(err, &ie) || ie.Index != 1 {
+			t.Fatalf("limit %d: err %v", limit, err)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package bar
+
+import (
+	"io"
+	"time"
+
+	"github.com/injoyai/bar/internal/terminal"
+)
+
+const (
+	modeAuto  = iota //自动,根据writer是否是终端判断
+	modeTTY          //终端模式,使用\r和ANSI控制符刷新同一行
+	modePlain        //纯文本模式,按进度或时间间隔输出新的一行,不带控制符
+)
+
+const (
+	DefaultPlainStep     = 0.1              //纯文本模式默认每前进10%输出一行
+	DefaultPlainInterval = time.Second * 10 //纯文本模式默认每隔10秒输出一行
+)
+
+// WithTTY 强制使用终端模式,使用\r和ANSI控制符刷新同一行
+func WithTTY() Option {
+	return func(b *Bar) {
+		b.mode = modeTTY
+	}
+}
+
+// WithPlain 强制使用纯文本模式(例如输出到日志文件或CI),不带控制符,
+// 每前进step(例 0.1 表示10%)或者每隔interval输出一行,小于等于0则使用默认值
+func WithPlain(step float64, interval time.Duration) Option {
+	return func(b *Bar) {
+		b.mode = modePlain
+		if step > 0 {
+			b.plainStep = step
+		}
+		if interval > 0 {
+			b.plainInterval = interval
+		}
+	}
+}
+
+// IsTTY 是否是终端模式,未强制设置时根据writer是否是终端判断
+func (this *Bar) IsTTY() bool {
+	switch this.mode {
+	case modeTTY:
+		return true
+	case modePlain:
+		return false
+	}
+	return isTerminal(this.writer)
+}
+
+// plainDue 纯文本模式下是否需要输出新的一行
+func (this *Bar) plainDue(final bool) bool {
+	now := this.Now()
+	rate := this.Rate()
+	due := final ||
+		this.plainTime.IsZero() ||
+		now.Sub(this.plainTime) >= this.plainInterval ||
+		(!this.Indeterminate() && rate-this.plainRate >= this.plainStep)
+	if due {
+		this.plainTime = now
+		this.plainRate = rate
+	}
+	return due
+}
+
+// isTerminal writer是否是终端,组中的进度条按组的模式判断
+func isTerminal(w io.Writer) bool {
+	if t, ok := w.(interface{ isTerminal() bool }); ok {
+		return t.isTerminal()
+	}
+	f, ok := w.(interface{ Fd() uintptr })
+	return ok && terminal.IsTerminal(f.Fd())
+}
@@ -0,0 +1,46 @@
+package bar
+
+import (
+	"time"
+)
+
+// Pause 暂停计时,暂停期间不计入耗时,速度和剩余时间,进度条和速度显示暂停状态
+func (this *Bar) Pause() *Bar {
+	this.pauseMu.Lock()
+	if this.pauseTime.IsZero() {
+		this.pauseTime = this.Now()
+	}
+	this.pauseMu.Unlock()
+	this.dirty.Store(true)
+	return this.Flush()
+}
+
+// Resume 恢复计时
+func (this *Bar) Resume() *Bar {
+	this.pauseMu.Lock()
+	if !this.pauseTime.IsZero() {
+		this.paused += this.Now().Sub(this.pauseTime)
+		this.pauseTime = time.Time{}
+	}
+	this.pauseMu.Unlock()
+	this.dirty.Store(true)
+	return this.Flush()
+}
+
+// Paused 是否暂停中
+func (this *Bar) Paused() bool {
+	this.pauseMu.Lock()
+	defer this.pauseMu.Unlock()
+	return !this.pauseTime.IsZero()
+}
+
+// Elapsed 已经耗时,不包括暂停的时间
+func (this *Bar) Elapsed() time.Duration {
+	this.pauseMu.Lock()
+	defer this.pauseMu.Unlock()
+	now := this.Now()
+	if !this.pauseTime.IsZero() {
+		now = this.pauseTime
+	}
+	return now.Sub(this.startTime) - this.paused
+}
@@ -0,0 +1,62 @@
+package bar_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/injoyai/bar"
+	"github.com/injoyai/bar/bartest"
+)
+
+// TestPause 暂停时进度条和速度显示暂停(不依赖颜色),暂停的时间不计入耗时和速度
+func TestPause(t *testing.T) {
+	b, c, r := bartest.New(
+		bar.WithTotal(100),
+		bar.WithFormat(
+			bar.WithPlan(func(p *bar.Plan) { p.SetWidth(10) }),
+			bar.WithSpeed(),
+			bar.WithUsed(),
+		),
+	)
+	b.Flush()
+	c.Add(time.Second)
+	b.Add(10).Flush()
+	if got, want := r.Last(), "[#         ]  10.0/s  1s"; got != want {
+		t.Fatalf("running: got %q, want %q", got, want)
+	}
+
+	b.Pause()
+	if got, want := r.Last(), "[#  暂停   ]  暂停  1s"; got != want {
+		t.Fatalf("paused: got %q, want %q", got, want)
+	}
+	c.Add(5 * time.Second)
+	b.Flush()
+	if got, want := r.Last(), "[#  暂停   ]  暂停  1s"; got != want {
+		t.Fatalf("paused: got %q, want %q", got, want)
+	}
+	if !b.Paused() || b.Elapsed() != time.Second {
+		t.Fatalf("paused %v, elapsed %v", b.Paused(), b.Elapsed())
+	}
+
+	b.Resume()
+	c.Add(time.Second)
+	b.Add(10).Flush()
+	if got, want := r.Last(), "[##        ]  10.0/s  2s"; got != want {
+		t.Fatalf("resumed: got %q, want %q", got, want)
+	}
+}
+
+// TestPausePlain 纯文本模式(去除颜色)也能看出暂停,不确定模式也显示
+func TestPausePlain(t *testing.T) {
+	r := bartest.NewRecorder()
+	b := bar.New(
+		bar.WithClock(bartest.NewClock()),
+		bar.WithWriter(r),
+		bar.WithPlain(0, 0),
+		bar.WithFormat(bar.WithPlan(func(p *bar.Plan) { p.SetWidth(10) })),
+	)
+	b.Add(1).Pause()
+	if got, want := r.Last(), "[## 暂停   ]"; got != want {
+		t.Fatalf("paused: got %q, want %q", got, want)
+	}
+}
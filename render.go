@@ -0,0 +1,120 @@
+package bar
+
+import (
+	"time"
+)
+
+// DefaultRefreshRate 默认的最大刷新频率,每秒的帧数
+const DefaultRefreshRate = 20
+
+// DefaultIdleTimeout 渲染循环空闲(没有变化)多久后退出,有新的变化时再启动
+const DefaultIdleTimeout = time.Second * 3
+
+// SetRefreshRate 设置最大刷新频率(每秒的帧数),小于等于0表示不限制
+func (this *Bar) SetRefreshRate(fps int) {
+	if fps <= 0 {
+		this.refresh = 0
+		return
+	}
+	this.refresh = time.Second / time.Duration(fps)
+}
+
+// Flush 刷新到writer,距离上次刷新不足最小间隔时只标记需要刷新,由渲染循环稍后刷新,
+// 进度完成时立即刷新最后一帧并关闭
+func (this *Bar) Flush() *Bar {
+	if this.Closed() {
+		return this
+	}
+	if this.refresh > 0 && !this.complete() &&
+		this.Now().Sub(time.Unix(0, this.lastFlush.Load())) < this.refresh {
+		this.dirty.Store(true)
+		this.run()
+		return this
+	}
+	return this.flush()
+}
+
+// flush 立即刷新,进度完成时关闭
+func (this *Bar) flush() *Bar {
+	this.mu.Lock()
+	if this.Closed() || this.finished || this.writer == nil {
+		this.mu.Unlock()
+		return this
+	}
+	final := this.complete()
+	if final {
+		this.setState(StateSucceeded, nil)
+	}
+	this.draw(final)
+	this.finished = final
+	this.mu.Unlock()
+
+	if final {
+		this.Close()
+	} else {
+		this.saveCheckpoint(false)
+	}
+	return this
+}
+
+// draw 输出一帧,需要加锁调用
+func (this *Bar) draw(final bool) {
+	if this.writer == nil {
+		return
+	}
+	now := this.Now().UnixNano()
+	if this.dirty.Swap(false) {
+		this.lastTime.Store(now)
+	}
+	this.lastFlush.Store(now)
+	if this.IsTTY() {
+		this.writer.Write([]byte(this.String()))
+	} else if this.plainDue(final) {
+		this.writer.Write([]byte(this.String() + "\n"))
+	}
+}
+
+// complete 是否已完成,不确定模式需要主动结束
+func (this *Bar) complete() bool {
+	total := this.Total()
+	return total > 0 && this.Current() >= total
+}
+
+// run 启动渲染循环,按刷新频率刷新有变化的内容,设置了定时刷新时定时刷新,
+// 关闭或者空闲超过 DefaultIdleTimeout 后退出,之后有新的变化时再启动,没有关闭的进度条不会一直占用协程
+func (this *Bar) run() {
+	if !this.looping.CompareAndSwap(false, true) {
+		return
+	}
+	tick := this.refresh
+	if tick <= 0 || (this.interval > 0 && this.interval < tick) {
+		tick = this.interval
+	}
+	if tick <= 0 {
+		tick = time.Second / DefaultRefreshRate
+	}
+	t := this.clock.NewTicker(tick)
+	go func() {
+		defer t.Stop()
+		for {
+			select {
+			case <-this.Done():
+				return
+			case <-t.C():
+				last := time.Unix(0, this.lastFlush.Load())
+				if this.dirty.Load() || (this.interval > 0 && this.Now().Sub(last) >= this.interval) {
+					this.flush()
+					continue
+				}
+				if this.interval > 0 || this.Now().Sub(last) < DefaultIdleTimeout {
+					continue
+				}
+				//空闲退出,退出前又有变化时继续,避免变化的一方以为循环还在运行而不启动
+				this.looping.Store(false)
+				if !this.dirty.Load() || !this.looping.CompareAndSwap(false, true) {
+					return
+				}
+			}
+		}
+	}()
+}
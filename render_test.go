@@ -0,0 +1,59 @@
+package bar_test
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/injoyai/bar"
+	"github.com/injoyai/bar/bartest"
+)
+
+// TestRenderLoopIdle 没有关闭的进度条,渲染循环空闲后退出,有新的变化时重新启动
+func TestRenderLoopIdle(t *testing.T) {
+	base := runtime.NumGoroutine()
+	b, c, r := bartest.New(
+		bar.WithFormat(bar.WithRateSize()),
+		bar.WithRefreshRate(10),
+	)
+
+	//第二次刷新被限流,由渲染循环补上
+	b.Add(1).Flush()
+	b.Add(1).Flush()
+	c.Add(100 * time.Millisecond)
+	waitLast(t, r, "2/-")
+
+	//空闲后退出
+	c.Add(bar.DefaultIdleTimeout + time.Second)
+	waitGoroutines(t, base)
+
+	//重新启动
+	b.Add(1).Flush()
+	b.Add(1).Flush()
+	c.Add(100 * time.Millisecond)
+	waitLast(t, r, "4/-")
+	b.Close()
+	waitGoroutines(t, base)
+}
+
+func waitLast(t *testing.T, r *bartest.Recorder, want string) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for r.Last() != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("last frame: got %q, want %q", r.Last(), want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func waitGoroutines(t *testing.T, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > n {
+		if time.Now().After(deadline) {
+			t.Fatalf("goroutines: got %d, want %d", runtime.NumGoroutine(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
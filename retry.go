@@ -0,0 +1,117 @@
+package bar
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// RetryFixed 固定间隔重试,attempts为最大尝试次数(包括第一次),<=0表示不限制
+func RetryFixed(attempts int, delay time.Duration) *RetryPolicy {
+	return &RetryPolicy{
+		attempts:   attempts,
+		delay:      delay,
+		multiplier: 1,
+	}
+}
+
+// RetryExponential 指数退避重试,每次间隔翻倍,最大不超过maxDelay(0表示不限制),默认20%的随机抖动
+func RetryExponential(attempts int, delay, maxDelay time.Duration) *RetryPolicy {
+	return &RetryPolicy{
+		attempts:   attempts,
+		delay:      delay,
+		maxDelay:   maxDelay,
+		multiplier: 2,
+		jitter:     0.2,
+	}
+}
+
+// RetryPolicy 重试策略
+type RetryPolicy struct {
+	attempts   int                  //最大尝试次数(包括第一次),<=0表示不限制
+	delay      time.Duration        //首次重试的间隔
+	maxDelay   time.Duration        //最大间隔,0表示不限制
+	multiplier float64              //间隔的倍数,<=1为固定间隔
+	jitter     float64              //随机抖动比例,0~1
+	maxElapsed time.Duration        //最大累计耗时,0表示不限制
+	retryable  func(err error) bool //判断错误是否可以重试,nil表示都可以重试
+}
+
+// SetMultiplier 设置间隔的倍数
+func (this *RetryPolicy) SetMultiplier(multiplier float64) *RetryPolicy {
+	this.multiplier = multiplier
+	return this
+}
+
+// SetJitter 设置随机抖动比例,例 0.2 表示间隔在 ±20% 内随机
+func (this *RetryPolicy) SetJitter(jitter float64) *RetryPolicy {
+	this.jitter = jitter
+	return this
+}
+
+// SetMaxElapsed 设置最大累计耗时,超过后不再重试
+func (this *RetryPolicy) SetMaxElapsed(maxElapsed time.Duration) *RetryPolicy {
+	this.maxElapsed = maxElapsed
+	return this
+}
+
+// SetRetryable 设置判断错误是否可以重试的函数
+func (this *RetryPolicy) SetRetryable(f func(err error) bool) *RetryPolicy {
+	this.retryable = f
+	return this
+}
+
+// Delay 第n次重试前的等待时间,n从1开始
+func (this *RetryPolicy) Delay(n int) time.Duration {
+	d := float64(this.delay)
+	for i := 1; i < n && this.multiplier > 1; i++ {
+		d *= this.multiplier
+		if this.maxDelay > 0 && d >= float64(this.maxDelay) {
+			break
+		}
+	}
+	if this.maxDelay > 0 && d > float64(this.maxDelay) {
+		d = float64(this.maxDelay)
+	}
+	if this.jitter > 0 {
+		d += d * this.jitter * (rand.Float64()*2 - 1)
+	}
+	return time.Duration(d)
+}
+
+// Do 执行函数,失败时按策略重试,返回最后一次的错误
+// onRetry 在每次重试前调用,n为第几次重试,err为上次的错误
+func (this *RetryPolicy) Do(ctx context.Context, f func(ctx context.Context) error, onRetry func(n int, err error)) error {
+	start := time.Now()
+	for n := 1; ; n++ {
+		err := f(ctx)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+		if this.retryable != nil && !this.retryable(err) {
+			return err
+		}
+		if this.attempts > 0 && n >= this.attempts {
+			return err
+		}
+		delay := this.Delay(n)
+		if this.maxElapsed > 0 && time.Since(start)+delay > this.maxElapsed {
+			return err
+		}
+		if onRetry != nil {
+			onRetry(n, err)
+		}
+		if delay > 0 {
+			t := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				t.Stop()
+				return err
+			case <-t.C:
+			}
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package bar
+
+import (
+	"errors"
+
+	"github.com/fatih/color"
+)
+
+// State 进度条的状态
+type State int
+
+const (
+	StateRunning   State = iota //运行中
+	StateSucceeded              //成功完成
+	StateFailed                 //失败
+	StateAborted                //中止(取消)
+)
+
+func (this State) String() string {
+	switch this {
+	case StateRunning:
+		return "运行中"
+	case StateSucceeded:
+		return "已完成"
+	case StateFailed:
+		return "失败"
+	case StateAborted:
+		return "已中止"
+	}
+	return "未知"
+}
+
+// ErrAborted 中止时的错误
+var ErrAborted = errors.New("已中止")
+
+// WithFinalResult 增加完成事件,并传入结束的状态和失败的错误,见 OnFinalResult
+func WithFinalResult(f func(b *Bar, state State, err error)) Option {
+	return func(b *Bar) {
+		b.OnFinalResult(f)
+	}
+}
+
+// OnFinalResult 增加完成事件,并传入结束的状态和失败的错误,
+// 和 OnFinal 不同,不会替换已有的完成事件(例如 WithFinalLn 的换行),在已有的事件之后执行
+func (this *Bar) OnFinalResult(f func(b *Bar, state State, err error)) {
+	old := this.onFinal
+	this.OnFinal(func(b *Bar) {
+		if old != nil {
+			old(b)
+		}
+		state, err := b.Result()
+		f(b, state, err)
+	})
+}
+
+// Finish 成功完成,进度设置到100%(不确定模式以当前数量作为总数量),刷新最后一帧并关闭
+func (this *Bar) Finish() *Bar {
+	if total := this.Total(); total > 0 {
+		this.SetCurrent(total)
+	} else {
+		this.SetTotal(this.current.Load())
+	}
+	return this.end(StateSucceeded, nil)
+}
+
+// Fail 失败结束,保留当前进度,最后一帧显示错误信息并关闭
+func (this *Bar) Fail(err error) *Bar {
+	return this.end(StateFailed, err)
+}
+
+// Abort 中止(取消),保留当前进度,最后一帧显示中止并关闭
+func (this *Bar) Abort() *Bar {
+	return this.end(StateAborted, ErrAborted)
+}
+
+// State 当前的状态,结束后不再变化
+func (this *Bar) State() State {
+	state, _ := this.Result()
+	return state
+}
+
+// Result 结束的状态和失败的错误,运行中返回 StateRunning
+func (this *Bar) Result() (State, error) {
+	this.stateMu.Lock()
+	defer this.stateMu.Unlock()
+	return this.state, this.stateErr
+}
+
+// end 记录结束的状态,刷新最后一帧并关闭
+func (this *Bar) end(state State, err error) *Bar {
+	if this.setState(state, err) {
+		this.dirty.Store(true)
+	}
+	this.Close()
+	return this
+}
+
+// setState 记录结束的状态,只有第一次有效
+func (this *Bar) setState(state State, err error) bool {
+	this.stateMu.Lock()
+	defer this.stateMu.Unlock()
+	if this.state != StateRunning {
+		return false
+	}
+	this.state = state
+	this.stateErr = err
+	return true
+}
+
+// stateText 结束状态的显示内容,例 ✓ , ✗ 错误信息
+func (this *Bar) stateText() string {
+	state, err := this.Result()
+	switch state {
+	case StateSucceeded:
+		return color.New(color.FgGreen).Sprint("✓")
+	case StateFailed:
+		if err == nil {
+			return color.New(color.FgRed).Sprint("✗")
+		}
+		return color.New(color.FgRed).Sprint("✗ " + err.Error())
+	case StateAborted:
+		return color.New(color.FgRed).Sprint("✗ " + ErrAborted.Error())
+	}
+	return ""
+}
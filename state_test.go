@@ -0,0 +1,91 @@
+package bar_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/injoyai/bar"
+	"github.com/injoyai/bar/bartest"
+	"github.com/injoyai/bar/internal/terminal"
+)
+
+func TestFinalState(t *testing.T) {
+	for _, v := range []struct {
+		name  string
+		end   func(b *bar.Bar)
+		state bar.State
+		want  string
+	}{
+		{"finish", func(b *bar.Bar) { b.Finish() }, bar.StateSucceeded, "10/10  ✓"},
+		{"fail", func(b *bar.Bar) { b.Fail(errors.New("断开")) }, bar.StateFailed, "3/10  ✗ 断开"},
+		{"abort", func(b *bar.Bar) { b.Abort() }, bar.StateAborted, "3/10  ✗ 已中止"},
+		{"close", func(b *bar.Bar) { b.Close() }, bar.StateAborted, "3/10  ✗ 已中止"},
+	} {
+		t.Run(v.name, func(t *testing.T) {
+			var final bar.State
+			b, _, r := bartest.New(
+				bar.WithTotal(10),
+				bar.WithFormat(bar.WithRateSize()),
+				bar.WithFinalResult(func(b *bar.Bar, state bar.State, err error) { final = state }),
+			)
+			b.Add(3).Flush()
+			v.end(b)
+			if final != v.state || b.State() != v.state {
+				t.Fatalf("state: got %v/%v, want %v", final, b.State(), v.state)
+			}
+			if got := r.Last(); !strings.HasSuffix(got, v.want) {
+				t.Fatalf("last frame: got %q, want %q", got, v.want)
+			}
+		})
+	}
+}
+
+// TestFinalStateWidth 行宽不足时,结束的状态优先于其它格式显示
+func TestFinalStateWidth(t *testing.T) {
+	for _, v := range []struct {
+		name string
+		op   bar.Option
+		want string
+	}{
+		{"default", bar.WithFormatDefault(), "  ✗ connection reset by peer"},
+		{"flex", bar.WithFormatDefault(func(p *bar.Plan) { p.SetFlex() }), "[###        ]  30/100  0.0/s  0s  ✗ connection reset by peer"},
+	} {
+		t.Run(v.name, func(t *testing.T) {
+			b, _, r := bartest.New(bar.WithTotal(100), bar.WithWidth(60), v.op)
+			b.Add(30).Flush()
+			b.Fail(errors.New("connection reset by peer"))
+			got := r.Last()
+			if !strings.HasSuffix(got, v.want) || terminal.Width(got) > 60 {
+				t.Fatalf("last frame: got %q, want suffix %q", got, v.want)
+			}
+		})
+	}
+}
+
+// TestFinalResultChain 结束事件不会替换已有的结束事件,最后一帧之后先换行
+func TestFinalResultChain(t *testing.T) {
+	var calls []string
+	b, _, r := bartest.New(
+		bar.WithTotal(10),
+		bar.WithFormat(bar.WithRateSize()),
+		bar.WithFinal(func(b *bar.Bar) { calls = append(calls, "final") }),
+		bar.WithFinalResult(func(b *bar.Bar, state bar.State, err error) { calls = append(calls, "result1") }),
+		bar.WithFinalResult(func(b *bar.Bar, state bar.State, err error) { calls = append(calls, "result2") }),
+	)
+	b.Finish()
+	if got := strings.Join(calls, ","); got != "final,result1,result2" {
+		t.Fatalf("calls: got %q", got)
+	}
+
+	calls = nil
+	b, _, r = bartest.New(
+		bar.WithTotal(10),
+		bar.WithFormat(bar.WithRateSize()),
+		bar.WithFinalResult(func(b *bar.Bar, state bar.State, err error) { calls = append(calls, "result") }),
+	)
+	b.Finish()
+	if got := r.String(); !strings.HasSuffix(got, "10/10  ✓\n") || len(calls) != 1 {
+		t.Fatalf("output: got %q, calls %q", got, calls)
+	}
+}